@@ -0,0 +1,62 @@
+package benchmarks
+
+import (
+	"encoding/json"
+	"testing"
+
+	kv "github.com/Clever/kayvee-go/v7"
+)
+
+// formatCorpus returns the corpus lines as the maps passed to a Formatter.
+func formatCorpus(corpus []logline) []map[string]interface{} {
+	lines := make([]map[string]interface{}, len(corpus))
+	for i, log := range corpus {
+		data := map[string]interface{}{"title": log.Title, "source": "perf", "level": "info"}
+		for k, v := range log.Data {
+			data[k] = v
+		}
+		lines[i] = data
+	}
+	return lines
+}
+
+func benchmarkFormat(b *testing.B, corpus []logline, format func(map[string]interface{})) {
+	lines := formatCorpus(corpus)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, data := range lines {
+			format(data)
+		}
+	}
+}
+
+func kvFormat(data map[string]interface{}) {
+	kv.Format(data)
+}
+
+func jsonMarshal(data map[string]interface{}) {
+	json.Marshal(data)
+}
+
+// kv.Format
+func BenchmarkFormatWithBasicCorpus(b *testing.B) {
+	benchmarkFormat(b, basicCorpus, kvFormat)
+}
+func BenchmarkFormatWithPathologicalCorpus(b *testing.B) {
+	benchmarkFormat(b, pathologicalCorpus, kvFormat)
+}
+func BenchmarkFormatWithRealisticCorpus(b *testing.B) {
+	benchmarkFormat(b, realisticCorpus, kvFormat)
+}
+
+// json.Marshal, which kv.Format used before its own encoder
+func BenchmarkJSONMarshalWithBasicCorpus(b *testing.B) {
+	benchmarkFormat(b, basicCorpus, jsonMarshal)
+}
+func BenchmarkJSONMarshalWithPathologicalCorpus(b *testing.B) {
+	benchmarkFormat(b, pathologicalCorpus, jsonMarshal)
+}
+func BenchmarkJSONMarshalWithRealisticCorpus(b *testing.B) {
+	benchmarkFormat(b, realisticCorpus, jsonMarshal)
+}
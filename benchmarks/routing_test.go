@@ -6,6 +6,7 @@ import (
 	"log"
 	"testing"
 
+	kv "github.com/Clever/kayvee-go/v7"
 	"github.com/Clever/kayvee-go/v7/logger"
 	"github.com/Clever/kayvee-go/v7/router"
 )
@@ -19,11 +20,19 @@ var basicCorpus []logline
 var pathologicalCorpus []logline
 var realisticCorpus []logline
 
+// realisticFields is realisticCorpus as typed fields.
+var realisticFields [][]logger.Field
+
 var noRouting logger.KayveeLogger
 var basicRouting logger.KayveeLogger
 var pathoRouting logger.KayveeLogger
 var realRouting logger.KayveeLogger
 
+// formatted and filtered format lines with kv.Format, without routing. filtered filters out
+// Info lines.
+var formatted *logger.Logger
+var filtered *logger.Logger
+
 func loadJSON(path string, o interface{}) error {
 	file, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -86,6 +95,19 @@ func init() {
 	basicRouting.SetConfig("perf", logger.Debug, formatter, output)
 	pathoRouting.SetConfig("perf", logger.Debug, formatter, output)
 	realRouting.SetConfig("perf", logger.Debug, formatter, output)
+
+	formatted = logger.NewConcreteLogger("perf")
+	formatted.SetConfig("perf", logger.Debug, kv.Format, output)
+	filtered = logger.NewConcreteLogger("perf")
+	filtered.SetConfig("perf", logger.Error, kv.Format, output)
+
+	for _, log := range realisticCorpus {
+		fields := make([]logger.Field, 0, len(log.Data))
+		for k, v := range log.Data {
+			fields = append(fields, logger.Any(k, v))
+		}
+		realisticFields = append(realisticFields, fields)
+	}
 }
 
 // No routing
@@ -187,3 +209,58 @@ func BenchmarkRealisticRoutingWithRealisticCorpus(b *testing.B) {
 		}
 	}
 }
+
+// Typed fields, compared with the same lines logged as maps
+func BenchmarkFormattedWithRealisticCorpus(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < len(realisticCorpus); i++ {
+			log := realisticCorpus[i]
+			formatted.InfoD(log.Title, log.Data)
+		}
+	}
+}
+func BenchmarkFormattedWithRealisticCorpusFields(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < len(realisticCorpus); i++ {
+			formatted.Info(realisticCorpus[i].Title, realisticFields[i]...)
+		}
+	}
+}
+func BenchmarkFormattedTypedFields(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		formatted.Info("request-finished", logger.String("path", "/students"), logger.Int("status", 200),
+			logger.Float64("response-ms", 12.5), logger.Bool("cached", false))
+	}
+}
+func BenchmarkFormattedTypedFieldsAsMap(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		formatted.InfoD("request-finished", logger.M{"path": "/students", "status": 200,
+			"response-ms": 12.5, "cached": false})
+	}
+}
+func BenchmarkRealisticRoutingWithRealisticCorpusFields(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < len(realisticCorpus); i++ {
+			realRouting.Info(realisticCorpus[i].Title, realisticFields[i]...)
+		}
+	}
+}
+func BenchmarkFilteredTypedFields(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		filtered.Info("request-finished", logger.String("path", "/students"), logger.Int("status", 200),
+			logger.Float64("response-ms", 12.5), logger.Bool("cached", false))
+	}
+}
+func BenchmarkFilteredTypedFieldsAsMap(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		filtered.InfoD("request-finished", logger.M{"path": "/students", "status": 200,
+			"response-ms": 12.5, "cached": false})
+	}
+}
@@ -1,5 +1,7 @@
 /*
-Package kayvee provides methods to output human and machine parseable strings, with a "key=val" format.
+Package kayvee provides methods to output human and machine parseable strings, as JSON objects
+(Format) or as logfmt "key=val" pairs (FormatLogfmt). ParseLogfmt reads logfmt lines back into maps
+that the router and validator packages accept.
 
 ## Example
 
@@ -0,0 +1,398 @@
+package kayvee
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"unicode/utf8"
+)
+
+// reservedKeys are written first by Format and FormatLogfmt, in this order.
+var reservedKeys = []string{"level", "source", "title", "type", "value"}
+
+func isReservedKey(k string) bool {
+	return reservedKeyRank(k) < len(reservedKeys)
+}
+
+// reservedKeyRank returns the position of k in reservedKeys, or len(reservedKeys) for other
+// keys.
+func reservedKeyRank(k string) int {
+	for i, reserved := range reservedKeys {
+		if k == reserved {
+			return i
+		}
+	}
+	return len(reservedKeys)
+}
+
+// maxPooledBufferSize bounds the buffers kept for reuse, so one very large line doesn't pin its
+// memory.
+const maxPooledBufferSize = 64 << 10
+
+// maxPooledFields bounds the field slices kept for reuse.
+const maxPooledFields = 1024
+
+// encodeState is the reusable state for encoding one line.
+type encodeState struct {
+	bytes.Buffer
+	// keys is a stack of the sorted keys of the maps being encoded.
+	keys []string
+	// fields holds the fields being encoded by AppendFields, and order their order.
+	fields []Field
+	order  []fieldOrder
+}
+
+var encodeStatePool = sync.Pool{
+	New: func() interface{} { return &encodeState{} },
+}
+
+// encodeJSON encodes data, with the fields in `overrides` replacing those with the same keys,
+// as a JSON object with the reserved keys first and the other keys sorted. Nested maps have
+// their keys sorted, as with json.Marshal. A value that can't be encoded is replaced by a string
+// describing the error. The overrides must not have reserved keys.
+func encodeJSON(data map[string]interface{}, overrides []Field) string {
+	e := newEncodeState()
+	e.WriteByte('{')
+	for _, k := range reservedKeys {
+		if v, ok := data[k]; ok {
+			e.writeField(k, v)
+		}
+	}
+	start := len(e.keys)
+	for k := range data {
+		if !isReservedKey(k) && findField(overrides, k) == nil {
+			e.keys = append(e.keys, k)
+		}
+	}
+	for _, f := range overrides {
+		e.keys = append(e.keys, f.Key)
+	}
+	keys := e.keys[start:]
+	slices.Sort(keys)
+	for _, k := range keys {
+		if f := findField(overrides, k); f != nil {
+			e.writeKey(k)
+			e.writeTypedValue(f)
+		} else {
+			e.writeField(k, data[k])
+		}
+	}
+	e.WriteByte('}')
+
+	out := e.String()
+	e.free()
+	return out
+}
+
+func newEncodeState() *encodeState {
+	e := encodeStatePool.Get().(*encodeState)
+	e.Reset()
+	e.keys = e.keys[:0]
+	e.fields = e.fields[:0]
+	e.order = e.order[:0]
+	return e
+}
+
+// free returns e to the pool, unless it grew too large to keep.
+func (e *encodeState) free() {
+	if e.Cap() <= maxPooledBufferSize && cap(e.fields) <= maxPooledFields {
+		encodeStatePool.Put(e)
+	}
+}
+
+// findField returns the last field in fields with key k, or nil.
+func findField(fields []Field, k string) *Field {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == k {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// writeField writes a top-level field.
+func (e *encodeState) writeField(k string, v interface{}) {
+	e.writeKey(k)
+	e.writeFieldValue(v)
+}
+
+// writeKey writes the key of a top-level field, after a comma if it isn't the first.
+func (e *encodeState) writeKey(k string) {
+	if e.Len() > 1 {
+		e.WriteByte(',')
+	}
+	writeJSONString(&e.Buffer, k)
+	e.WriteByte(':')
+}
+
+// writeFieldValue writes the value of a top-level field. If it can't be encoded, the error is
+// written in its place, so that the rest of the line is kept.
+func (e *encodeState) writeFieldValue(v interface{}) {
+	start := e.Len()
+	if err := e.writeValue(v); err != nil {
+		e.Truncate(start)
+		writeJSONString(&e.Buffer, fmt.Sprintf("Error marshaling value in map, err: %s, value: %+v", err.Error(), v))
+	}
+}
+
+// sortedKeys pushes the sorted keys of m onto the key stack and returns them. The caller pops
+// them with popKeys when it is done.
+func (e *encodeState) sortedKeys(m map[string]interface{}) []string {
+	start := len(e.keys)
+	for k := range m {
+		e.keys = append(e.keys, k)
+	}
+	keys := e.keys[start:]
+	slices.Sort(keys)
+	return keys
+}
+
+func (e *encodeState) popKeys(keys []string) {
+	e.keys = e.keys[:len(e.keys)-len(keys)]
+}
+
+func (e *encodeState) writeMap(m map[string]interface{}) error {
+	if m == nil {
+		e.WriteString("null")
+		return nil
+	}
+	keys := e.sortedKeys(m)
+	defer e.popKeys(keys)
+	e.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			e.WriteByte(',')
+		}
+		writeJSONString(&e.Buffer, k)
+		e.WriteByte(':')
+		if err := e.writeValue(m[k]); err != nil {
+			return err
+		}
+	}
+	e.WriteByte('}')
+	return nil
+}
+
+// writeValue encodes v as encoding/json would, using a type switch for the common types and
+// falling back to json.Marshal for the others.
+func (e *encodeState) writeValue(v interface{}) error {
+	switch val := v.(type) {
+	case nil:
+		e.WriteString("null")
+	case string:
+		writeJSONString(&e.Buffer, val)
+	case bool:
+		e.WriteString(strconv.FormatBool(val))
+	case int:
+		e.writeInt(int64(val))
+	case int8:
+		e.writeInt(int64(val))
+	case int16:
+		e.writeInt(int64(val))
+	case int32:
+		e.writeInt(int64(val))
+	case int64:
+		e.writeInt(val)
+	case uint:
+		e.writeUint(uint64(val))
+	case uint8:
+		e.writeUint(uint64(val))
+	case uint16:
+		e.writeUint(uint64(val))
+	case uint32:
+		e.writeUint(uint64(val))
+	case uint64:
+		e.writeUint(val)
+	case float32:
+		return e.writeFloat(float64(val), 32)
+	case float64:
+		return e.writeFloat(val, 64)
+	case map[string]interface{}:
+		return e.writeMap(val)
+	case []interface{}:
+		if val == nil {
+			e.WriteString("null")
+			return nil
+		}
+		e.WriteByte('[')
+		for i, elem := range val {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			if err := e.writeValue(elem); err != nil {
+				return err
+			}
+		}
+		e.WriteByte(']')
+	case []string:
+		if val == nil {
+			e.WriteString("null")
+			return nil
+		}
+		e.WriteByte('[')
+		for i, elem := range val {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			writeJSONString(&e.Buffer, elem)
+		}
+		e.WriteByte(']')
+	case []map[string]interface{}:
+		if val == nil {
+			e.WriteString("null")
+			return nil
+		}
+		e.WriteByte('[')
+		for i, elem := range val {
+			if i > 0 {
+				e.WriteByte(',')
+			}
+			if err := e.writeMap(elem); err != nil {
+				return err
+			}
+		}
+		e.WriteByte(']')
+	case json.Number, json.Marshaler, encoding.TextMarshaler:
+		return e.writeMarshaled(v)
+	default:
+		return e.writeReflected(v)
+	}
+	return nil
+}
+
+var genericMapType = reflect.TypeOf(map[string]interface{}(nil))
+
+// writeReflected encodes named types of the common kinds, like LogLevel or logger.M, without
+// json.Marshal.
+func (e *encodeState) writeReflected(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		writeJSONString(&e.Buffer, rv.String())
+	case reflect.Bool:
+		e.WriteString(strconv.FormatBool(rv.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		e.writeUint(rv.Uint())
+	case reflect.Map:
+		if !rv.Type().ConvertibleTo(genericMapType) {
+			return e.writeMarshaled(v)
+		}
+		return e.writeMap(rv.Convert(genericMapType).Interface().(map[string]interface{}))
+	default:
+		return e.writeMarshaled(v)
+	}
+	return nil
+}
+
+func (e *encodeState) writeMarshaled(v interface{}) error {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.Write(bs)
+	return nil
+}
+
+func (e *encodeState) writeInt(i int64) {
+	var scratch [20]byte
+	e.Write(strconv.AppendInt(scratch[:0], i, 10))
+}
+
+func (e *encodeState) writeUint(u uint64) {
+	var scratch [20]byte
+	e.Write(strconv.AppendUint(scratch[:0], u, 10))
+}
+
+// writeFloat formats f like encoding/json: without an exponent unless it is very small or
+// large, and as the shortest representation that round-trips.
+func (e *encodeState) writeFloat(f float64, bits int) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return &json.UnsupportedValueError{Value: reflect.ValueOf(f), Str: strconv.FormatFloat(f, 'g', -1, bits)}
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) ||
+			bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	var scratch [32]byte
+	b := strconv.AppendFloat(scratch[:0], f, format, -1, bits)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	e.Write(b)
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeJSONString writes s as a JSON string, escaped like json.Marshal does, including its
+// escaping of HTML characters and invalid UTF-8.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			buf.WriteString(s[start:i])
+			switch b {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(b)
+			case '\b':
+				buf.WriteString(`\b`)
+			case '\f':
+				buf.WriteString(`\f`)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[b>>4])
+				buf.WriteByte(hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.WriteString(s[start:i])
+			buf.WriteString("\ufffd")
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf.WriteString(s[start:i])
+			buf.WriteString(`\u202`)
+			buf.WriteByte(hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
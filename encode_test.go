@@ -0,0 +1,147 @@
+package kayvee
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type namedMap map[string]interface{}
+
+type textValue struct{ s string }
+
+func (t textValue) MarshalText() ([]byte, error) { return []byte("text:" + t.s), nil }
+
+func TestEncodeJSONMatchesMarshal(t *testing.T) {
+	values := []interface{}{
+		nil, "", "plain", `quote " backslash \ slash /`, "<html> & more", "\x00\x01\x1f\x7f",
+		"\b\f\n\r\t", "héllo wörld ✓ 🎉", "invalid \xff utf8", "line sep ",
+		true, false, 0, -1, int8(-8), int16(16), int32(-32), int64(math.MaxInt64), uint(1),
+		uint8(8), uint16(16), uint32(32), uint64(math.MaxUint64),
+		0.0, 1.5, -2.25, 1e20, 1e21, 1e-6, 1e-7, 123456789.123, float32(3.14), float32(1e-7),
+		float32(1e21), math.SmallestNonzeroFloat64, math.MaxFloat64,
+		map[string]interface{}{"b": 1, "a": []interface{}{"x", 2.5, nil}, "<": ">"},
+		map[string]interface{}(nil), []interface{}(nil), []interface{}{}, []string{"a", "b"},
+		[]map[string]interface{}{{"rule": "r1"}, {"rule": "r2"}},
+		namedMap{"z": 1, "a": namedMap{"nested": true}}, namedMap(nil),
+		LogLevel("info"), []byte("bytes"), time.Date(2026, 10, 16, 4, 5, 6, 7, time.UTC),
+		textValue{"v"}, map[string]int{"b": 2, "a": 1}, struct{ A string }{"a"},
+		errors.New("opaque"), json.Number("12.50"),
+	}
+	for _, v := range values {
+		expected, err := json.Marshal(map[string]interface{}{"k": v})
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), encodeJSON(map[string]interface{}{"k": v}, nil), "value %#v", v)
+	}
+}
+
+func TestEncodeJSONKeyOrder(t *testing.T) {
+	out := encodeJSON(map[string]interface{}{
+		"zeta": 1, "value": 2, "alpha": 3, "type": "gauge", "title": "t", "source": "s",
+		"level": "info", "nested": map[string]interface{}{"title": 1, "level": 2},
+	}, nil)
+	assert.Equal(t, `{"level":"info","source":"s","title":"t","type":"gauge","value":2,`+
+		`"alpha":3,"nested":{"level":2,"title":1},"zeta":1}`, out)
+	assert.Equal(t, `{}`, encodeJSON(map[string]interface{}{}, nil))
+	assert.Equal(t, `{"a":1}`, encodeJSON(map[string]interface{}{"a": 1}, nil))
+}
+
+func TestEncodeJSONErrors(t *testing.T) {
+	data := map[string]interface{}{
+		"title":  "t",
+		"nan":    math.NaN(),
+		"inf":    map[string]interface{}{"deep": []interface{}{math.Inf(1)}},
+		"fn":     func() {},
+		"after":  "kept",
+		"nested": map[string]interface{}{"ok": 1},
+	}
+	out := encodeJSON(data, nil)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &decoded))
+	assert.Equal(t, "t", decoded["title"])
+	assert.Equal(t, "kept", decoded["after"])
+	assert.Equal(t, map[string]interface{}{"ok": 1.0}, decoded["nested"])
+	assert.Equal(t, "Error marshaling value in map, err: json: unsupported value: NaN, value: NaN", decoded["nan"])
+	assert.True(t, strings.HasPrefix(decoded["inf"].(string),
+		"Error marshaling value in map, err: json: unsupported value: +Inf, value: "))
+	assert.True(t, strings.HasPrefix(decoded["fn"].(string),
+		"Error marshaling value in map, err: json: unsupported type: func(), value: "))
+	// the caller's map is not changed
+	assert.True(t, math.IsNaN(data["nan"].(float64)))
+}
+
+func TestEncodeJSONConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				data := map[string]interface{}{"i": i, "j": j, "s": strings.Repeat("x", j*100)}
+				expected, _ := json.Marshal(data)
+				assert.Equal(t, string(expected), encodeJSON(data, nil))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestFormatConformance(t *testing.T) {
+	// apart from key order, Format writes the same bytes as json.Marshal
+	file, err := ioutil.ReadFile("tests.json")
+	assert.NoError(t, err)
+	var tests Tests
+	assert.NoError(t, json.Unmarshal(file, &tests))
+	for _, spec := range tests.FormatTests {
+		data := map[string]interface{}{}
+		for k, v := range spec.Input["data"].(map[string]interface{}) {
+			data[k] = v
+		}
+		for _, f := range envFields {
+			data[f.Key] = f.String
+		}
+		expected, err := json.Marshal(data)
+		assert.NoError(t, err)
+		assert.Equal(t, len(expected), len(Format(data)), spec.Title)
+		compareJSONStrings(t, string(expected), Format(data))
+	}
+}
+
+func benchmarkData() map[string]interface{} {
+	return map[string]interface{}{
+		"title":       "request-finished",
+		"level":       "info",
+		"source":      "my-service",
+		"method":      "GET",
+		"path":        "/v1/students/<id>",
+		"status-code": 200,
+		"response-ms": 12.75,
+		"ok":          true,
+		"params":      map[string]interface{}{"page": 2, "limit": 100, "filter": "grade = 5"},
+		"ids":         []interface{}{"a1", "b2", "c3"},
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	data := benchmarkData()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		Format(data)
+	}
+}
+
+func BenchmarkJSONMarshal(b *testing.B) {
+	data := benchmarkData()
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		json.Marshal(data)
+	}
+}
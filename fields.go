@@ -0,0 +1,151 @@
+package kayvee
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// FieldType says which of a Field's values is set.
+type FieldType uint8
+
+// Constants used to define different FieldTypes supported
+const (
+	// AnyType fields hold their value in Interface, and are formatted like a map value.
+	AnyType FieldType = iota
+	// StringType fields hold their value in String.
+	StringType
+	// IntType fields hold their value in Integer.
+	IntType
+	// FloatType fields hold the math.Float64bits of their value in Integer.
+	FloatType
+	// BoolType fields hold 1 in Integer for true, and 0 for false.
+	BoolType
+	// DurationType fields hold a time.Duration in Integer.
+	DurationType
+	// ErrorType fields hold an error in Interface, and are formatted as its message.
+	ErrorType
+	// SkipType fields are left out of the line.
+	SkipType
+)
+
+// Field is a key and a typed value. AppendFields formats a line from fields without boxing
+// typed values in interfaces or building a map, so loggers use them on hot paths.
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Interface interface{}
+}
+
+// Value returns the field's value as it is stored in a map passed to Format.
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case StringType:
+		return f.String
+	case IntType:
+		return f.Integer
+	case FloatType:
+		return math.Float64frombits(uint64(f.Integer))
+	case BoolType:
+		return f.Integer == 1
+	case DurationType:
+		return time.Duration(f.Integer)
+	case ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			return err.Error()
+		}
+		return nil
+	case SkipType:
+		return nil
+	}
+	return f.Interface
+}
+
+// AppendFields appends to dst the line that Format writes for a map holding `fields`, where a
+// field replaces any earlier field with the same key, and fields of SkipType are left out.
+func AppendFields(dst []byte, fields []Field) []byte {
+	if redactionPolicy.Load() != nil {
+		// the redaction policy works on maps
+		data := make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			if f.Type != SkipType {
+				data[f.Key] = f.Value()
+			}
+		}
+		return append(dst, Format(data)...)
+	}
+
+	e := newEncodeState()
+	for _, f := range fields {
+		if f.Type != SkipType {
+			e.fields = append(e.fields, f)
+		}
+	}
+	e.fields = append(e.fields, envFields...)
+	// sort the positions of the fields rather than the fields themselves, which are larger.
+	// The sort is stable, so of the fields with the same key, the last one added is last.
+	for i := range e.fields {
+		e.order = append(e.order, fieldOrder{rank: reservedKeyRank(e.fields[i].Key), index: i})
+	}
+	slices.SortStableFunc(e.order, func(a, b fieldOrder) int {
+		if a.rank != b.rank {
+			return a.rank - b.rank
+		}
+		return strings.Compare(e.fields[a.index].Key, e.fields[b.index].Key)
+	})
+
+	e.WriteByte('{')
+	for i, o := range e.order {
+		f := &e.fields[o.index]
+		if i+1 < len(e.order) && e.fields[e.order[i+1].index].Key == f.Key {
+			continue
+		}
+		e.writeKey(f.Key)
+		e.writeTypedValue(f)
+	}
+	e.WriteByte('}')
+
+	dst = append(dst, e.Bytes()...)
+	clear(e.fields)
+	e.free()
+	return dst
+}
+
+// fieldOrder is the position of a field in the line: reserved keys first, and then the others
+// sorted.
+type fieldOrder struct {
+	rank  int
+	index int
+}
+
+func (e *encodeState) writeTypedValue(f *Field) {
+	switch f.Type {
+	case StringType:
+		writeJSONString(&e.Buffer, f.String)
+	case IntType, DurationType:
+		e.writeInt(f.Integer)
+	case FloatType:
+		start := e.Len()
+		if err := e.writeFloat(math.Float64frombits(uint64(f.Integer)), 64); err != nil {
+			e.Truncate(start)
+			e.writeFieldValue(f.Value())
+		}
+	case BoolType:
+		if f.Integer == 1 {
+			e.WriteString("true")
+		} else {
+			e.WriteString("false")
+		}
+	case ErrorType:
+		if err, ok := f.Interface.(error); ok && err != nil {
+			writeJSONString(&e.Buffer, err.Error())
+		} else {
+			e.WriteString("null")
+		}
+	default:
+		e.writeFieldValue(f.Interface)
+	}
+}
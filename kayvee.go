@@ -1,10 +1,11 @@
 package kayvee
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
 	"os"
+	"sync/atomic"
+
+	"github.com/Clever/kayvee-go/v7/redact"
 )
 
 var (
@@ -14,6 +15,9 @@ var (
 	podShortname string
 	podRegion    string
 	podAccount   string
+
+	// envFields are the fields from the environment that Format adds to every line.
+	envFields []Field
 )
 
 func init() {
@@ -37,6 +41,20 @@ func init() {
 	if os.Getenv("_POD_ACCOUNT") != "" {
 		podAccount = os.Getenv("_POD_ACCOUNT")
 	}
+
+	for _, f := range []Field{
+		{Key: "deploy_env", String: deployEnv},
+		{Key: "wf_id", String: workflowID},
+		{Key: "pod-id", String: podID},
+		{Key: "pod-shortname", String: podShortname},
+		{Key: "pod-region", String: podRegion},
+		{Key: "pod-account", String: podAccount},
+	} {
+		if f.String != "" {
+			f.Type = StringType
+			envFields = append(envFields, f)
+		}
+	}
 }
 
 // Log Levels:
@@ -59,48 +77,36 @@ const (
 	defaultFlags = log.LstdFlags | log.Lshortfile
 )
 
-// Format converts a map to a string of space-delimited key=val pairs
+var redactionPolicy atomic.Pointer[redact.Policy]
+
+// SetRedactionPolicy sets the redaction policy that Format applies to every line, and that
+// loggers without a policy of their own apply before routing. Passing nil turns it off.
+func SetRedactionPolicy(p *redact.Policy) {
+	redactionPolicy.Store(p)
+}
+
+// RedactionPolicy returns the redaction policy set with SetRedactionPolicy, or nil.
+func RedactionPolicy() *redact.Policy {
+	return redactionPolicy.Load()
+}
+
+// Format converts a map to a JSON string. The level, source, title, type and value come first,
+// followed by the other keys sorted. A value that can't be marshaled is replaced by a string
+// describing the error. FormatLogfmt writes the same line as logfmt. data is not modified.
 func Format(data map[string]interface{}) string {
-	if deployEnv != "" {
-		data["deploy_env"] = deployEnv
-	}
-	if workflowID != "" {
-		data["wf_id"] = workflowID
-	}
-	if podID != "" {
-		data["pod-id"] = podID
-	}
-	if podShortname != "" {
-		data["pod-shortname"] = podShortname
-	}
-	if podRegion != "" {
-		data["pod-region"] = podRegion
-	}
-	if podAccount != "" {
-		data["pod-account"] = podAccount
-	}
-	formattedString, err := json.Marshal(data)
-	if err != nil {
-		for k, v := range data {
-			_, err := json.Marshal(v)
-			if err != nil {
-				data[k] = fmt.Sprintf("Error marshaling value in map, err: %s, value: %+v", err.Error(), v)
-			}
-		}
-		formattedString, _ = json.Marshal(data)
-	}
-	return string(formattedString)
+	return encodeJSON(redactionPolicy.Load().Apply(data), envFields)
 }
 
 // FormatLog is similar to Format, but takes additional reserved params to promote logging best-practices
 func FormatLog(source string, level LogLevel, title string, data map[string]interface{}) string {
-	if data == nil {
-		data = make(map[string]interface{})
+	record := make(map[string]interface{}, len(data)+3)
+	for k, v := range data {
+		record[k] = v
 	}
-	data["source"] = source
-	data["level"] = level
-	data["title"] = title
-	return Format(data)
+	record["source"] = source
+	record["level"] = level
+	record["title"] = title
+	return Format(record)
 }
 
 // Logger is an interface satisfied by all loggers that use kayvee to Log results
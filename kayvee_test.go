@@ -3,8 +3,10 @@ package kayvee
 import (
 	"encoding/json"
 	"io/ioutil"
+	"sync"
 	"testing"
 
+	"github.com/Clever/kayvee-go/v7/redact"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -84,3 +86,38 @@ func Test_MapWithInterfaceWithFunctionField(t *testing.T) {
 	out := FormatLog("test_source", "test_level", "test_title", data)
 	assert.Contains(t, out, expected)
 }
+
+func TestFormatRedaction(t *testing.T) {
+	p, err := redact.New([]redact.Rule{{Keys: []string{"email"}, Action: redact.Drop}})
+	assert.NoError(t, err)
+	SetRedactionPolicy(p)
+	defer SetRedactionPolicy(nil)
+
+	data := map[string]interface{}{"email": "a@example.com", "id": 1}
+	compareJSONStrings(t, `{"id":1}`, Format(data))
+	assert.Equal(t, "a@example.com", data["email"])
+}
+
+// TestFormatConcurrentSharedData is meant to be run with -race.
+func TestFormatConcurrentSharedData(t *testing.T) {
+	data := map[string]interface{}{"title": "t", "id": 1}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				compareJSONStrings(t, `{"title":"t","id":1}`, Format(data))
+				FormatLogfmt(data)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, map[string]interface{}{"title": "t", "id": 1}, data)
+}
+
+func TestFormatLogDoesNotModifyData(t *testing.T) {
+	data := map[string]interface{}{"id": 1}
+	compareJSONStrings(t, `{"source":"s","level":"info","title":"t","id":1}`, FormatLog("s", Info, "t", data))
+	assert.Equal(t, map[string]interface{}{"id": 1}, data)
+}
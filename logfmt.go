@@ -0,0 +1,323 @@
+package kayvee
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonNumber matches the strings that ParseLogfmt reads as numbers.
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// FormatLogfmt is like Format, but writes the line as logfmt: space-separated key=val pairs.
+// The level, source, title, type and value come first, followed by the other keys sorted.
+// Nested maps are flattened into dotted keys, e.g. `{"user": {"id": 1}}` becomes `user.id=1`,
+// matching the field paths used by log routing matchers, and empty nested maps are written as
+// `{}`. Strings are quoted when they contain spaces, quotes, `=` or control characters, or would
+// otherwise be read back as another type; slices and other values are written as JSON.
+//
+// Keys that would collide once flattened, like a top-level "a.b" and the "b" field of a nested
+// map "a", are all kept: the key seen later in sorted order gets a `_1`, `_2`, ... suffix.
+func FormatLogfmt(data map[string]interface{}) string {
+	fields := logfmtFields{flat: map[string]interface{}{}, interior: map[string]bool{}}
+	fields.flatten("", redactionPolicy.Load().Apply(data))
+	flat := fields.flat
+	for _, f := range envFields {
+		flat[f.Key] = f.String
+	}
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ordered := make([]string, 0, len(keys))
+	for _, k := range reservedKeys {
+		if _, ok := flat[k]; ok {
+			ordered = append(ordered, k)
+		}
+	}
+	for _, k := range keys {
+		if !isReservedKey(k) {
+			ordered = append(ordered, k)
+		}
+	}
+
+	var sb strings.Builder
+	for i, k := range ordered {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(logfmtValue(flat[k]))
+	}
+	return sb.String()
+}
+
+// logfmtFields holds the flattened fields of a line being formatted as logfmt.
+type logfmtFields struct {
+	// flat maps each dotted key to its value.
+	flat map[string]interface{}
+	// interior holds the proper prefixes of the keys in flat, e.g. "a" and "a.b" for "a.b.c".
+	interior map[string]bool
+}
+
+// flatten adds the fields of m to f, with nested maps flattened into dotted keys. Keys are
+// added in sorted order, so that collisions are resolved the same way every time.
+func (f *logfmtFields) flatten(prefix string, m map[string]interface{}) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := logfmtKey(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		nested, ok := stringKeyedMap(m[k])
+		switch {
+		case !ok:
+			f.add(key, m[k])
+		case len(nested) == 0:
+			// written as `{}`, so that the field isn't lost
+			f.add(key, nested)
+		default:
+			f.flatten(key, nested)
+		}
+	}
+}
+
+// add sets key to v. If key can't be added without ParseLogfmt reading it back as, or in
+// conflict with, another key, a `_<n>` suffix is added to the segment of key that conflicts.
+func (f *logfmtFields) add(key string, v interface{}) {
+	parts := strings.Split(key, ".")
+	orig := append([]string{}, parts...)
+	for n := 1; ; n++ {
+		i := f.conflict(parts)
+		if i < 0 {
+			break
+		}
+		parts[i] = fmt.Sprintf("%s_%d", orig[i], n)
+	}
+	for i := 1; i < len(parts); i++ {
+		f.interior[strings.Join(parts[:i], ".")] = true
+	}
+	f.flat[strings.Join(parts, ".")] = v
+}
+
+// conflict returns the index of the first segment of the key made of parts that conflicts with
+// a key already added, or -1 if it doesn't conflict.
+func (f *logfmtFields) conflict(parts []string) int {
+	for i := 1; i < len(parts); i++ {
+		if _, ok := f.flat[strings.Join(parts[:i], ".")]; ok {
+			return i - 1
+		}
+	}
+	key := strings.Join(parts, ".")
+	if _, ok := f.flat[key]; ok || f.interior[key] {
+		return len(parts) - 1
+	}
+	return -1
+}
+
+// stringKeyedMap returns v as a map[string]interface{} if it is a map with string keys, like
+// logger.M.
+func stringKeyedMap(v interface{}) (map[string]interface{}, bool) {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m, true
+}
+
+// logfmtKey replaces the characters that can't appear in a logfmt key with underscores.
+func logfmtKey(k string) string {
+	if k == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, k)
+}
+
+func logfmtValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return logfmtString(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case map[string]interface{}:
+		if len(val) == 0 {
+			return "{}"
+		}
+	case error:
+		return logfmtString(val.Error())
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", val)
+	}
+
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return logfmtString(fmt.Sprintf("%+v", v))
+	}
+	switch v.(type) {
+	case float32, float64:
+		return string(bs)
+	}
+	// values that marshal to a JSON number, like time.Duration, are written as that number,
+	// and values that marshal to a JSON string, like time.Time, as that string
+	if jsonNumber.Match(bs) {
+		return string(bs)
+	}
+	var s string
+	if json.Unmarshal(bs, &s) == nil {
+		return logfmtString(s)
+	}
+	return logfmtString(string(bs))
+}
+
+// logfmtString quotes s if it can't be written as a bare value, or would be read back by
+// ParseLogfmt as something other than the same string.
+func logfmtString(s string) string {
+	if s == "" || s == "true" || s == "false" || s == "null" || s == "{}" || jsonNumber.MatchString(s) {
+		return strconv.Quote(s)
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || !strconv.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// ParseLogfmt parses a logfmt line, like those written by FormatLogfmt, into a map. Dotted keys
+// become nested maps. Bare values that are numbers, `true`, `false`, `null` or `{}` become
+// float64, bool, nil and an empty map, as they would when decoding JSON; quoted values are
+// always strings. A key with no `=` is set to true.
+func ParseLogfmt(line string) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	i := 0
+	for {
+		for i < len(line) && isLogfmtSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return out, nil
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && !isLogfmtSpace(line[i]) {
+			if line[i] == '"' {
+				return nil, fmt.Errorf("logfmt: unexpected '\"' in key at offset %d", i)
+			}
+			i++
+		}
+		key := line[start:i]
+		if key == "" {
+			return nil, fmt.Errorf("logfmt: missing key at offset %d", start)
+		}
+		if i == len(line) || line[i] != '=' {
+			if err := setLogfmtField(out, key, true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		i++ // skip '='
+
+		var value interface{}
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("logfmt: unterminated quoted value for key '%s'", key)
+			}
+			s, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("logfmt: invalid quoted value for key '%s': %s", key, err)
+			}
+			value = s
+			i = end + 1
+		} else {
+			start = i
+			for i < len(line) && !isLogfmtSpace(line[i]) {
+				i++
+			}
+			value = parseLogfmtBareValue(line[start:i])
+		}
+		if err := setLogfmtField(out, key, value); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func isLogfmtSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func parseLogfmtBareValue(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	case "{}":
+		return map[string]interface{}{}
+	}
+	if jsonNumber.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// setLogfmtField sets the dotted key in out, creating the nested maps along its path.
+func setLogfmtField(out map[string]interface{}, key string, value interface{}) error {
+	path := strings.Split(key, ".")
+	m := out
+	for _, part := range path[:len(path)-1] {
+		next, exists := m[part]
+		if !exists {
+			nested := map[string]interface{}{}
+			m[part] = nested
+			m = nested
+			continue
+		}
+		nested, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("logfmt: key '%s' conflicts with a value set for '%s'", key, part)
+		}
+		m = nested
+	}
+	last := path[len(path)-1]
+	if _, ok := m[last].(map[string]interface{}); ok {
+		return fmt.Errorf("logfmt: key '%s' conflicts with nested keys under it", key)
+	}
+	m[last] = value
+	return nil
+}
@@ -0,0 +1,205 @@
+package kayvee
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLogfmt(t *testing.T) {
+	out := FormatLogfmt(map[string]interface{}{
+		"title":  "request_finished",
+		"level":  "info",
+		"source": "my-app",
+		"value":  1.5,
+		"type":   "gauge",
+		"zeta":   3,
+		"alpha":  true,
+	})
+	assert.Equal(t, "level=info source=my-app title=request_finished type=gauge value=1.5 "+
+		"alpha=true deploy_env=testing wf_id=abc123 zeta=3", out)
+}
+
+func TestFormatLogfmtQuoting(t *testing.T) {
+	tests := []struct {
+		value    interface{}
+		expected string
+	}{
+		{"plain", `k=plain`},
+		{"", `k=""`},
+		{"two words", `k="two words"`},
+		{`say "hi"`, `k="say \"hi\""`},
+		{"a=b", `k="a=b"`},
+		{`back\slash`, `k="back\\slash"`},
+		{"line\nbreak", `k="line\nbreak"`},
+		{"tab\there", `k="tab\there"`},
+		{"héllo", `k=héllo`},
+		{"true", `k="true"`},
+		{"null", `k="null"`},
+		{"42", `k="42"`},
+		{"-1.5e3", `k="-1.5e3"`},
+		{"1.2.3", `k=1.2.3`},
+		{nil, `k=null`},
+		{false, `k=false`},
+		{int64(-7), `k=-7`},
+		{uint8(7), `k=7`},
+		{0.25, `k=0.25`},
+		{time.Second, `k=1000000000`},
+		{errors.New("it broke: bad"), `k="it broke: bad"`},
+		{[]interface{}{"a", 1}, `k="[\"a\",1]"`},
+		{time.Date(2026, 10, 16, 4, 5, 6, 0, time.UTC), `k=2026-10-16T04:05:06Z`},
+	}
+	for _, test := range tests {
+		out := FormatLogfmt(map[string]interface{}{"k": test.value})
+		assert.Contains(t, out, test.expected+" ", "value %#v", test.value)
+	}
+}
+
+func TestFormatLogfmtKeys(t *testing.T) {
+	out := FormatLogfmt(map[string]interface{}{"a key": 1, `q"=`: 2, "": 3})
+	assert.Contains(t, out, "_=3 ")
+	assert.Contains(t, out, " a_key=1")
+	assert.Contains(t, out, ` q__=2`)
+}
+
+type stringMap map[string]interface{}
+
+func TestFormatLogfmtNested(t *testing.T) {
+	out := FormatLogfmt(map[string]interface{}{
+		"title": "t",
+		"user": map[string]interface{}{
+			"id":   "u1",
+			"role": stringMap{"name": "admin", "scopes": []string{"read"}},
+		},
+	})
+	assert.Equal(t, `title=t deploy_env=testing user.id=u1 user.role.name=admin `+
+		`user.role.scopes="[\"read\"]" wf_id=abc123`, out)
+}
+
+func TestParseLogfmt(t *testing.T) {
+	data, err := ParseLogfmt(`level=info title="hello world" n=1.5 neg=-2 ok=true no=false ` +
+		`nothing=null quoted="42" empty= esc="a\"b\\c\nd" bare user.id=u1 user.role.name=admin` + "\t")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"level":   "info",
+		"title":   "hello world",
+		"n":       1.5,
+		"neg":     -2.0,
+		"ok":      true,
+		"no":      false,
+		"nothing": nil,
+		"quoted":  "42",
+		"empty":   "",
+		"esc":     "a\"b\\c\nd",
+		"bare":    true,
+		"user": map[string]interface{}{
+			"id":   "u1",
+			"role": map[string]interface{}{"name": "admin"},
+		},
+	}, data)
+
+	data, err = ParseLogfmt("  ")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{}, data)
+}
+
+func TestParseLogfmtErrors(t *testing.T) {
+	for _, line := range []string{
+		`=value`,
+		`a="unterminated`,
+		`a="bad \q escape"`,
+		`k"ey=1`,
+		`a=1 a.b=2`,
+		`a.b=2 a=1`,
+	} {
+		_, err := ParseLogfmt(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestLogfmtRoundTrip(t *testing.T) {
+	data := map[string]interface{}{
+		"level":   "error",
+		"title":   "it failed",
+		"count":   3,
+		"ratio":   0.5,
+		"status":  "200",
+		"empty":   "",
+		"flag":    false,
+		"nothing": nil,
+		"msg":     "quote \" and \\ and = and\nnewline",
+		"nested":  map[string]interface{}{"a": map[string]interface{}{"b": "deep value"}},
+	}
+	parsed, err := ParseLogfmt(FormatLogfmt(data))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"level":      "error",
+		"title":      "it failed",
+		"count":      3.0,
+		"ratio":      0.5,
+		"status":     "200",
+		"empty":      "",
+		"flag":       false,
+		"nothing":    nil,
+		"msg":        "quote \" and \\ and = and\nnewline",
+		"nested":     map[string]interface{}{"a": map[string]interface{}{"b": "deep value"}},
+		"deploy_env": "testing",
+		"wf_id":      "abc123",
+	}, parsed)
+}
+
+func TestLogfmtRoundTripEmptyMaps(t *testing.T) {
+	data := map[string]interface{}{
+		"title":  "t",
+		"empty":  map[string]interface{}{},
+		"nested": map[string]interface{}{"a": stringMap{}, "b": 1},
+		"braces": "{}",
+	}
+	out := FormatLogfmt(data)
+	assert.Contains(t, out, " empty={} ")
+	assert.Contains(t, out, ` braces="{}" `)
+	parsed, err := ParseLogfmt(out)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"title":      "t",
+		"empty":      map[string]interface{}{},
+		"nested":     map[string]interface{}{"a": map[string]interface{}{}, "b": 1.0},
+		"braces":     "{}",
+		"deploy_env": "testing",
+		"wf_id":      "abc123",
+	}, parsed)
+}
+
+func TestLogfmtRoundTripCollidingKeys(t *testing.T) {
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			data:     map[string]interface{}{"a.b": 1, "a": map[string]interface{}{"b": 2}},
+			expected: map[string]interface{}{"a": map[string]interface{}{"b": 2.0, "b_1": 1.0}},
+		},
+		{
+			data:     map[string]interface{}{"a": 1, "a.b": 2},
+			expected: map[string]interface{}{"a": 1.0, "a_1": map[string]interface{}{"b": 2.0}},
+		},
+		{
+			data:     map[string]interface{}{"a": map[string]interface{}{"b.c": 1}, "a.b": 2},
+			expected: map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1.0}, "b_1": 2.0}},
+		},
+		{
+			data:     map[string]interface{}{"a key": 1, "a_key": 2},
+			expected: map[string]interface{}{"a_key": 1.0, "a_key_1": 2.0},
+		},
+	} {
+		out := FormatLogfmt(tc.data)
+		assert.Equal(t, out, FormatLogfmt(tc.data), "collisions are resolved deterministically")
+		parsed, err := ParseLogfmt(out)
+		assert.NoError(t, err, out)
+		delete(parsed, "deploy_env")
+		delete(parsed, "wf_id")
+		assert.Equal(t, tc.expected, parsed, out)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sync"
 	"time"
 
+	kv "github.com/Clever/kayvee-go/v7"
 	"github.com/Clever/kayvee-go/v7/logger"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -73,6 +74,9 @@ type Config struct {
 	FirehosePutRecordBatchMaxTime time.Duration
 	// FirehoseAPI defaults to an API object configured with Region, but can be overriden here.
 	FirehoseAPI firehoseiface.FirehoseAPI
+	// TimestampFormat records the time each event was logged, which is earlier than when its
+	// batch is sent, as a "timestamp" field. Defaults to KAYVEE_TIMESTAMP_FORMAT.
+	TimestampFormat logger.TimestampFormat
 	// ErrLogger is a logger used to make sure errors from goroutines still get surfaced. Defaults to basic logger.Logger
 	ErrLogger logger.KayveeLogger
 	// VPCEndpoint determines whether to use the VPC endpoint of firehose. Default: true
@@ -84,7 +88,12 @@ type Config struct {
 func New(c Config) (*Logger, error) {
 	l := logger.New(c.DBName)
 	al := &Logger{KayveeLogger: l}
+	// Write parses each line, so it must be JSON even when KAYVEE_FORMAT is pretty or logfmt
+	l.SetFormatter(kv.Format)
 	l.SetOutput(al)
+	if lg, ok := l.(*logger.Logger); ok && c.TimestampFormat != logger.NoTimestamp {
+		lg.SetTimestampFormat(c.TimestampFormat)
+	}
 	env, dbname, streamName := c.Environment, c.DBName, c.StreamName
 	if dbname != "" && streamName != "" {
 		return nil, errors.New("cannot specify both DBName and StreamName in logger config")
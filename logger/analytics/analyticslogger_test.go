@@ -2,6 +2,7 @@ package analytics
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Clever/kayvee-go/v7/logger"
 	"github.com/aws/aws-sdk-go/aws"
@@ -35,6 +36,29 @@ func TestLogger(t *testing.T) {
 				l.InfoD("test-title", logger.M{"foo": "bar"})
 			},
 		},
+		{
+			name: "keeps the timestamp as event time",
+			alc: Config{
+				Environment:     "testenv",
+				DBName:          "testdb",
+				TimestampFormat: logger.TimestampEpochMillis,
+			},
+			mockExpectations: func(mf *MockFirehoseAPI) {
+				mf.EXPECT().PutRecordBatch(&firehose.PutRecordBatchInput{
+					DeliveryStreamName: aws.String("testenv--testdb"),
+					Records: []*firehose.Record{
+						{Data: []byte(`{"foo":"bar","timestamp":1760590000123}
+`)},
+					},
+				}).Return(&firehose.PutRecordBatchOutput{FailedPutCount: aws.Int64(0)}, nil)
+			},
+			ops: func(l logger.KayveeLogger) {
+				l.(*Logger).KayveeLogger.(*logger.Logger).SetClock(func() time.Time {
+					return time.UnixMilli(1760590000123)
+				})
+				l.InfoD("test-title", logger.M{"foo": "bar"})
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,315 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy determines what an asynchronous logger does with a log line when its queue is
+// full.
+type OverflowPolicy int
+
+// Constants used to define different OverflowPolicies supported
+const (
+	// OverflowBlock blocks the caller until there is room in the queue
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the line being logged
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest queued line to make room for the line being logged
+	OverflowDropOldest
+	// OverflowDropBelowLevel drops the line being logged if its level is below
+	// AsyncConfig.DropBelowLevel, and blocks otherwise
+	OverflowDropBelowLevel
+)
+
+const (
+	defaultAsyncQueueSize      = 1024
+	defaultDroppedLogsInterval = time.Minute
+	droppedLogsTitle           = "kayvee-dropped-logs"
+)
+
+// AsyncConfig configures asynchronous output for a Logger. See Logger.SetAsync.
+type AsyncConfig struct {
+	// QueueSize is the maximum number of formatted lines waiting to be written. Defaults to 1024.
+	QueueSize int
+	// OverflowPolicy determines what happens to a line logged while the queue is full.
+	// Defaults to OverflowBlock.
+	OverflowPolicy OverflowPolicy
+	// DropBelowLevel is the level below which lines are dropped when the queue is full. Only
+	// used with OverflowDropBelowLevel.
+	DropBelowLevel LogLevel
+	// DroppedLogsInterval is how often a `kayvee-dropped-logs` counter is logged if any lines
+	// were dropped. Defaults to one minute.
+	DroppedLogsInterval time.Duration
+}
+
+// asyncEntry is a formatted log line waiting to be written by write. If flushed is non-nil the
+// entry is a flush marker: it is closed once every entry queued before it has been written.
+type asyncEntry struct {
+	level   LogLevel
+	write   func()
+	flushed chan struct{}
+}
+
+// deferredFormatLogger is a formatLogger that can format a line now and write it later.
+type deferredFormatLogger interface {
+	formatLogger
+	// formatDeferred formats data and returns a function that writes the result.
+	formatDeferred(data map[string]interface{}) func()
+}
+
+// asyncQueue is the queue and background goroutine shared by an asyncFormatLogger and the
+// loggers derived from it by withFormatter and withOutput.
+type asyncQueue struct {
+	lg      *Logger
+	config  AsyncConfig
+	queue   chan asyncEntry
+	dropped int64
+	done    chan struct{}
+	stopped chan struct{}
+
+	// closedL guards closed, so that no lines are queued once the queue has been closed.
+	closedL sync.RWMutex
+	closed  bool
+}
+
+// asyncFormatLogger implements the formatLogger interface by formatting lines on the caller's
+// goroutine and handing them to a background goroutine to be written.
+type asyncFormatLogger struct {
+	// fl formats and writes the lines. Each queued line is written by the fl that formatted it,
+	// so changing the formatter or output doesn't affect lines already queued.
+	fl deferredFormatLogger
+	q  *asyncQueue
+}
+
+// SetAsync makes the logger (and its children) write log lines from a background goroutine,
+// so a slow output doesn't block logging calls. Lines are formatted on the caller's goroutine
+// and held in a bounded queue; config.OverflowPolicy determines what happens when it is full.
+// Call Flush to wait for queued lines to be written, and Close before exiting to drain the
+// queue and stop the background goroutine. It can be combined with SetSinks, in either order.
+func (l *Logger) SetAsync(config AsyncConfig) error {
+	l.settingsL.Lock()
+	defer l.settingsL.Unlock()
+	s := *l.settings.Load()
+
+	var fl deferredFormatLogger
+	switch f := s.fLogger.(type) {
+	case *asyncFormatLogger:
+		if err := f.q.close(); err != nil {
+			return err
+		}
+		fl = f.fl
+	case deferredFormatLogger:
+		fl = f
+	default:
+		return errors.New("async output is not supported by this logger")
+	}
+
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultAsyncQueueSize
+	}
+	if config.DroppedLogsInterval <= 0 {
+		config.DroppedLogsInterval = defaultDroppedLogsInterval
+	}
+	q := &asyncQueue{
+		lg:      l,
+		config:  config,
+		queue:   make(chan asyncEntry, config.QueueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go q.run()
+	go q.reportDropped()
+	s.fLogger = &asyncFormatLogger{fl: fl, q: q}
+	l.settings.Store(&s)
+	return nil
+}
+
+// Flush blocks until all lines logged before the call have been written, then flushes the
+// outputs and sinks that buffer lines, i.e. those with a `Flush() error` method like
+// OTLPLogsExporter. Errors flushing outputs are logged to the standard logger.
+func (l *Logger) Flush() {
+	fl := l.settings.Load().fLogger
+	if afl, ok := fl.(*asyncFormatLogger); ok {
+		afl.q.flush()
+	}
+	for _, output := range formatLoggerOutputs(fl) {
+		if f, ok := output.(interface{ Flush() error }); ok {
+			if err := f.Flush(); err != nil {
+				log.Printf("WARN: kayvee could not flush log output: %s", err)
+			}
+		}
+	}
+}
+
+// Close drains the queue of an asynchronous logger and stops its background goroutine. Lines
+// logged after Close are written synchronously. It is a no-op for synchronous loggers. Outputs
+// and sinks are left open, since they belong to the caller and may be shared by other loggers.
+func (l *Logger) Close() error {
+	if afl, ok := l.settings.Load().fLogger.(*asyncFormatLogger); ok {
+		return afl.q.close()
+	}
+	return nil
+}
+
+// formatLoggerOutputs returns the writers fl writes lines to.
+func formatLoggerOutputs(fl formatLogger) []io.Writer {
+	switch f := fl.(type) {
+	case *defaultFormatLogger:
+		return []io.Writer{f.logWriter.Writer()}
+	case *teeFormatLogger:
+		outputs := make([]io.Writer, len(f.sinks))
+		for i, sink := range f.sinks {
+			outputs[i] = sink.fl.logWriter.Writer()
+		}
+		return outputs
+	case *asyncFormatLogger:
+		return formatLoggerOutputs(f.fl)
+	}
+	return nil
+}
+
+// formatAndLog implements the formatLogger interface for *asyncFormatLogger.
+func (afl *asyncFormatLogger) formatAndLog(data map[string]interface{}) {
+	entry := asyncEntry{
+		level: logLevelFromData(data),
+		write: afl.fl.formatDeferred(data),
+	}
+
+	q := afl.q
+	q.closedL.RLock()
+	defer q.closedL.RUnlock()
+	if q.closed {
+		entry.write()
+		return
+	}
+	q.enqueue(entry)
+}
+
+// enqueue adds entry to the queue, applying the overflow policy if the queue is full.
+func (q *asyncQueue) enqueue(entry asyncEntry) {
+	select {
+	case q.queue <- entry:
+		return
+	default:
+	}
+
+	switch q.config.OverflowPolicy {
+	case OverflowDropNewest:
+		atomic.AddInt64(&q.dropped, 1)
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.queue <- entry:
+				return
+			default:
+			}
+			select {
+			case oldest := <-q.queue:
+				if oldest.flushed != nil {
+					// never drop flush markers; requeue it behind the lines still waiting
+					q.queue <- oldest
+				} else {
+					atomic.AddInt64(&q.dropped, 1)
+				}
+			default:
+			}
+		}
+	case OverflowDropBelowLevel:
+		if entry.level < q.config.DropBelowLevel {
+			atomic.AddInt64(&q.dropped, 1)
+			return
+		}
+		q.queue <- entry
+	default:
+		q.queue <- entry
+	}
+}
+
+// run writes queued lines until the queue is closed.
+func (q *asyncQueue) run() {
+	defer close(q.stopped)
+	for entry := range q.queue {
+		if entry.flushed != nil {
+			close(entry.flushed)
+			continue
+		}
+		entry.write()
+	}
+}
+
+// reportDropped periodically logs how many lines were dropped because the queue was full.
+func (q *asyncQueue) reportDropped() {
+	ticker := time.NewTicker(q.config.DroppedLogsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-ticker.C:
+			q.logDropped()
+		}
+	}
+}
+
+func (q *asyncQueue) flush() {
+	q.closedL.RLock()
+	if q.closed {
+		q.closedL.RUnlock()
+		return
+	}
+	flushed := make(chan struct{})
+	q.queue <- asyncEntry{flushed: flushed}
+	q.closedL.RUnlock()
+	<-flushed
+}
+
+func (q *asyncQueue) close() error {
+	q.closedL.Lock()
+	if q.closed {
+		q.closedL.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.done)
+	close(q.queue)
+	q.closedL.Unlock()
+	<-q.stopped
+
+	// report lines dropped since the last report; this is written synchronously now that the
+	// queue is closed
+	q.logDropped()
+	return nil
+}
+
+// logDropped logs a counter of the lines dropped since it was last called, if there were any.
+func (q *asyncQueue) logDropped() {
+	if dropped := atomic.SwapInt64(&q.dropped, 0); dropped > 0 {
+		q.lg.CounterD(droppedLogsTitle, int(dropped), M{})
+	}
+}
+
+// withFormatter implements the formatLogger interface for *asyncFormatLogger.
+func (afl *asyncFormatLogger) withFormatter(formatter Formatter) formatLogger {
+	return &asyncFormatLogger{fl: afl.fl.withFormatter(formatter).(deferredFormatLogger), q: afl.q}
+}
+
+// withOutput implements the formatLogger interface for *asyncFormatLogger.
+func (afl *asyncFormatLogger) withOutput(output io.Writer) formatLogger {
+	return &asyncFormatLogger{fl: afl.fl.withOutput(output).(deferredFormatLogger), q: afl.q}
+}
+
+// logLevelFromData returns the LogLevel named by data["level"], or Trace if it is missing.
+func logLevelFromData(data map[string]interface{}) LogLevel {
+	name, _ := data["level"].(string)
+	for lvl, lvlName := range logLevelNames {
+		if name == lvlName {
+			return lvl
+		}
+	}
+	return Trace
+}
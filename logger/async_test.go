@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedWriter blocks every write until release is called, and signals on entered the first
+// time a write starts.
+type gatedWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{entered: make(chan struct{}, 1), gate: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	select {
+	case w.entered <- struct{}{}:
+	default:
+	}
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) release() {
+	close(w.gate)
+}
+
+// lines returns the title of each line written, with the value of counters appended.
+func (w *gatedWriter) lines(t *testing.T) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(w.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &data))
+		title := data["title"].(string)
+		if value, ok := data["value"]; ok {
+			title = fmt.Sprintf("%s=%v", title, value)
+		}
+		out = append(out, title)
+	}
+	return out
+}
+
+// newBlockedAsyncLogger returns an async logger whose writer is blocked writing "line0".
+func newBlockedAsyncLogger(t *testing.T, config AsyncConfig) (*Logger, *gatedWriter) {
+	w := newGatedWriter()
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(w)
+	require.NoError(t, lg.SetAsync(config))
+	lg.Info("line0")
+	<-w.entered
+	return lg, w
+}
+
+func TestAsyncFlush(t *testing.T) {
+	w := newGatedWriter()
+	w.release()
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(w)
+	require.NoError(t, lg.SetAsync(AsyncConfig{}))
+
+	child := lg.With(M{"a": "b"})
+	lg.Info("line0")
+	child.Info("line1")
+	lg.Flush()
+	assert.Equal(t, []string{"line0", "line1"}, w.lines(t))
+
+	require.NoError(t, lg.Close())
+	lg.Info("line2")
+	assert.Equal(t, []string{"line0", "line1", "line2"}, w.lines(t), "logs synchronously after Close")
+}
+
+func TestAsyncSetOutputKeepsQueuedLines(t *testing.T) {
+	lg, w := newBlockedAsyncLogger(t, AsyncConfig{})
+	lg.Info("line1")
+	w2 := newGatedWriter()
+	w2.release()
+	lg.SetOutput(w2)
+	lg.Info("line2")
+
+	w.release()
+	require.NoError(t, lg.Close())
+	assert.Equal(t, []string{"line0", "line1"}, w.lines(t), "lines queued before SetOutput")
+	assert.Equal(t, []string{"line2"}, w2.lines(t))
+}
+
+func TestAsyncDropNewest(t *testing.T) {
+	lg, w := newBlockedAsyncLogger(t, AsyncConfig{QueueSize: 2, OverflowPolicy: OverflowDropNewest})
+	for _, title := range []string{"line1", "line2", "line3", "line4"} {
+		lg.Info(title)
+	}
+	w.release()
+	require.NoError(t, lg.Close())
+	assert.Equal(t, []string{"line0", "line1", "line2", "kayvee-dropped-logs=2"}, w.lines(t))
+}
+
+func TestAsyncDropOldest(t *testing.T) {
+	lg, w := newBlockedAsyncLogger(t, AsyncConfig{QueueSize: 2, OverflowPolicy: OverflowDropOldest})
+	for _, title := range []string{"line1", "line2", "line3", "line4"} {
+		lg.Info(title)
+	}
+	w.release()
+	require.NoError(t, lg.Close())
+	assert.Equal(t, []string{"line0", "line3", "line4", "kayvee-dropped-logs=2"}, w.lines(t))
+}
+
+func TestAsyncDropBelowLevel(t *testing.T) {
+	lg, w := newBlockedAsyncLogger(t, AsyncConfig{
+		QueueSize:      2,
+		OverflowPolicy: OverflowDropBelowLevel,
+		DropBelowLevel: Warning,
+	})
+	lg.Info("line1")
+	lg.Warn("line2")
+	lg.Info("line3")
+
+	logged := make(chan struct{})
+	go func() {
+		lg.Error("line4")
+		close(logged)
+	}()
+	select {
+	case <-logged:
+		t.Fatal("Error line should block while the queue is full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	w.release()
+	<-logged
+	require.NoError(t, lg.Close())
+	assert.Equal(t, []string{"line0", "line1", "line2", "line4", "kayvee-dropped-logs=1"}, w.lines(t))
+}
+
+func TestAsyncReportsDroppedPeriodically(t *testing.T) {
+	lg, w := newBlockedAsyncLogger(t, AsyncConfig{
+		QueueSize:           1,
+		OverflowPolicy:      OverflowDropNewest,
+		DroppedLogsInterval: 5 * time.Millisecond,
+	})
+	lg.Info("line1")
+	lg.Info("line2")
+	w.release()
+
+	assert.Eventually(t, func() bool {
+		lg.Flush()
+		lines := w.lines(t)
+		return len(lines) == 3 && lines[2] == "kayvee-dropped-logs=1"
+	}, time.Second, 5*time.Millisecond)
+	require.NoError(t, lg.Close())
+}
+
+func TestSetAsyncUnsupported(t *testing.T) {
+	mockLogger := NewMockCountLogger("testing")
+	lg, ok := mockLogger.logger.(*Logger)
+	require.True(t, ok)
+	assert.Error(t, lg.SetAsync(AsyncConfig{}))
+}
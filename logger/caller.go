@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// CallerAnnotation is an enum used to denote which caller location fields are added to lines.
+type CallerAnnotation int32
+
+// Constants used to define different CallerAnnotations supported
+const (
+	// NoCaller adds no caller location. This is the default.
+	NoCaller CallerAnnotation = iota
+	// CallerLine adds `caller`, the file and line that logged the line, e.g. "server/handler.go:42".
+	CallerLine
+	// CallerLineAndFunc adds `caller` and `func`, the function that logged the line, e.g.
+	// "server.(*Handler).ServeHTTP".
+	CallerLineAndFunc
+)
+
+// Field names used by caller annotation.
+const (
+	CallerKey = "caller"
+	FuncKey   = "func"
+)
+
+// maxCallerDepth bounds the number of frames searched for the caller.
+const maxCallerDepth = 32
+
+// loggerDir is the directory of the logger package. Frames in it, or in the packages under it
+// like analytics, are part of kayvee rather than the caller.
+var loggerDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// SetCallerAnnotation sets which caller location fields the logger and its children add to
+// each line. The caller is the first function outside of kayvee's logger packages, so calls
+// through helpers like InfoD, Timer.Stop or the analytics logger are attributed to their
+// caller. Wrappers outside of kayvee should use WithCallerSkip.
+func (l *Logger) SetCallerAnnotation(annotation CallerAnnotation) {
+	l.callerAnnotation.Store(int32(annotation))
+}
+
+// WithCallerSkip returns a child logger, like With, that skips `skip` more frames when finding
+// the caller. Wrappers around the logger use it so their own frames are not reported, e.g. a
+// helper function that calls InfoD uses WithCallerSkip(1).
+func (l *Logger) WithCallerSkip(skip int) *Logger {
+	child := l.with(nil)
+	child.callerSkip += skip
+	return child
+}
+
+// addCaller adds the caller location fields to data, unless data already has them.
+func (l *Logger) addCaller(data map[string]interface{}) {
+	annotation := CallerAnnotation(l.callerAnnotation.Load())
+	if annotation == NoCaller {
+		return
+	}
+	if _, ok := data[CallerKey]; ok {
+		return
+	}
+	frame, ok := l.callerFrame()
+	if !ok {
+		return
+	}
+	addCallerFields(data, annotation, frame)
+}
+
+func addCallerFields(data map[string]interface{}, annotation CallerAnnotation, frame runtime.Frame) {
+	data[CallerKey] = callerLocation(frame)
+	if annotation == CallerLineAndFunc {
+		data[FuncKey] = callerFunc(frame)
+	}
+}
+
+// callerLocation returns the value of the `caller` field for frame.
+func callerLocation(frame runtime.Frame) string {
+	return fmt.Sprintf("%s:%d", trimCallerPath(frame.File), frame.Line)
+}
+
+// callerFunc returns the value of the `func` field for frame.
+func callerFunc(frame runtime.Frame) string {
+	return frame.Function[strings.LastIndexByte(frame.Function, '/')+1:]
+}
+
+// addCallerFromPC adds the caller location fields for the program counter pc to data, e.g. for
+// a slog.Record, whose PC is the caller of the slog.Logger method.
+func (l *Logger) addCallerFromPC(data map[string]interface{}, pc uintptr) {
+	annotation := CallerAnnotation(l.callerAnnotation.Load())
+	if annotation == NoCaller || pc == 0 {
+		return
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	addCallerFields(data, annotation, frame)
+}
+
+// callerFrame returns the first frame outside of kayvee's logger packages, skipping
+// l.callerSkip more frames after it.
+func (l *Logger) callerFrame() (runtime.Frame, bool) {
+	pcs := make([]uintptr, maxCallerDepth)
+	// skip runtime.Callers and callerFrame
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	skip := l.callerSkip
+	for {
+		frame, more := frames.Next()
+		if !isLoggerFrame(frame) {
+			if skip == 0 {
+				return frame, true
+			}
+			skip--
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// isLoggerFrame returns whether frame is in kayvee's logger packages, including the wrapper
+// methods the compiler generates for types that embed a KayveeLogger.
+func isLoggerFrame(frame runtime.Frame) bool {
+	if frame.File == "<autogenerated>" {
+		return true
+	}
+	if strings.HasSuffix(frame.File, "_test.go") {
+		return false
+	}
+	dir := filepath.Dir(frame.File)
+	return dir == loggerDir || strings.HasPrefix(dir, loggerDir+"/")
+}
+
+// trimCallerPath keeps the last directory of a file path, e.g.
+// "github.com/Clever/app/server/handler.go" becomes "server/handler.go".
+func trimCallerPath(path string) string {
+	idx := strings.LastIndexByte(path, '/')
+	if idx == -1 {
+		return path
+	}
+	if prev := strings.LastIndexByte(path[:idx], '/'); prev != -1 {
+		return path[prev+1:]
+	}
+	return path
+}
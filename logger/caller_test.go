@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// embeddingLogger wraps a KayveeLogger by embedding it, like analytics.Logger.
+type embeddingLogger struct {
+	KayveeLogger
+}
+
+// logHelper is a user wrapper that logs on behalf of its caller.
+func logHelper(lg *Logger, title string) {
+	lg.WithCallerSkip(1).InfoD(title, M{})
+}
+
+// here returns the "caller" expected for the line after the one calling it.
+func here() string {
+	_, file, line, _ := runtime.Caller(1)
+	return fmt.Sprintf("%s:%d", trimCallerPath(file), line+1)
+}
+
+func TestCallerAnnotation(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+
+	lg.Info("off")
+	assert.NotContains(t, decodeLines(t, buf)[0], CallerKey, "caller annotation is opt-in")
+
+	lg.SetCallerAnnotation(CallerLineAndFunc)
+	expected := []string{}
+	buf.Reset()
+
+	expected = append(expected, here())
+	lg.Info("info")
+	expected = append(expected, here())
+	lg.WarnD("warn-d", M{})
+	expected = append(expected, here())
+	lg.With(M{"a": "b"}).ErrorE("error-e", fmt.Errorf("e"), nil)
+	timer := lg.Timer("timer")
+	expected = append(expected, here())
+	timer.Stop()
+	var kl KayveeLogger = &embeddingLogger{KayveeLogger: lg}
+	expected = append(expected, here())
+	kl.InfoD("embedded", M{})
+	expected = append(expected, here())
+	logHelper(lg, "helper")
+	expected = append(expected, here())
+	NewSlogLogger(lg).Info("slog")
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 8)
+	lines = append(lines[:3], lines[4:]...) // timer-start
+	for i, line := range lines {
+		assert.Equal(t, expected[i], line[CallerKey], "%v", line["title"])
+		assert.Equal(t, "logger.TestCallerAnnotation", line[FuncKey], "%v", line["title"])
+	}
+}
+
+func TestCallerAnnotationLineOnly(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	lg.SetCallerAnnotation(CallerLine)
+
+	expected := here()
+	lg.InfoD("info", M{})
+	line := decodeLines(t, buf)[0]
+	assert.Equal(t, expected, line[CallerKey])
+	assert.NotContains(t, line, FuncKey)
+
+	buf.Reset()
+	lg.InfoD("info", M{CallerKey: "set/by-caller.go:1"})
+	assert.Equal(t, "set/by-caller.go:1", decodeLines(t, buf)[0][CallerKey])
+
+	buf.Reset()
+	lg.SetCallerAnnotation(NoCaller)
+	NewSlogLogger(lg).Info("slog", slog.Int("a", 1))
+	assert.NotContains(t, decodeLines(t, buf)[0], CallerKey)
+}
+
+func TestTrimCallerPath(t *testing.T) {
+	assert.Equal(t, "server/handler.go", trimCallerPath("/go/src/github.com/Clever/app/server/handler.go"))
+	assert.Equal(t, "app/main.go", trimCallerPath("app/main.go"))
+	assert.Equal(t, "main.go", trimCallerPath("main.go"))
+}
@@ -1,6 +1,11 @@
 package logger
 
-import "context"
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
 
 type loggerKeyType struct{}
 
@@ -24,3 +29,83 @@ func FromContext(ctx context.Context) KayveeLogger {
 	}
 	return New("")
 }
+
+// ContextExtractor returns fields to be logged from values stored in a context. Extractors are
+// run by the *Ctx logging methods, e.g. InfoCtx, and by SlogHandler.
+type ContextExtractor func(ctx context.Context) M
+
+type namedContextExtractor struct {
+	name      string
+	extractor ContextExtractor
+}
+
+var (
+	contextExtractorsL sync.RWMutex
+	contextExtractors  = []namedContextExtractor{
+		{name: "trace", extractor: traceContextExtractor},
+	}
+)
+
+// RegisterContextExtractor registers an extractor under `name`, replacing any extractor
+// previously registered with that name. Extractors run in the order they were registered, and
+// fields from later extractors override fields from earlier ones. Fields in the data map passed
+// to the logging method always take precedence.
+//
+// The "trace" extractor, which adds the W3C trace and span IDs of the active OpenTelemetry span
+// as `trace_id` and `span_id`, is registered by default.
+func RegisterContextExtractor(name string, extractor ContextExtractor) {
+	contextExtractorsL.Lock()
+	defer contextExtractorsL.Unlock()
+	for i, e := range contextExtractors {
+		if e.name == name {
+			contextExtractors[i].extractor = extractor
+			return
+		}
+	}
+	contextExtractors = append(contextExtractors, namedContextExtractor{name: name, extractor: extractor})
+}
+
+// UnregisterContextExtractor removes the extractor registered under `name`, if any.
+func UnregisterContextExtractor(name string) {
+	contextExtractorsL.Lock()
+	defer contextExtractorsL.Unlock()
+	for i, e := range contextExtractors {
+		if e.name == name {
+			contextExtractors = append(contextExtractors[:i:i], contextExtractors[i+1:]...)
+			return
+		}
+	}
+}
+
+// addContextFields adds the fields returned by all registered extractors to data, without
+// overwriting keys that are already set.
+func addContextFields(ctx context.Context, data map[string]interface{}) {
+	if ctx == nil {
+		return
+	}
+	fields := M{}
+	contextExtractorsL.RLock()
+	for _, e := range contextExtractors {
+		for k, v := range e.extractor(ctx) {
+			fields[k] = v
+		}
+	}
+	contextExtractorsL.RUnlock()
+	for k, v := range fields {
+		if _, ok := data[k]; !ok {
+			data[k] = v
+		}
+	}
+}
+
+// traceContextExtractor returns the trace and span IDs of the OpenTelemetry span in ctx.
+func traceContextExtractor(ctx context.Context) M {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return M{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	}
+}
@@ -1,10 +1,13 @@
 package logger
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
+	kv "github.com/Clever/kayvee-go/v7"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestFromContext(t *testing.T) {
@@ -17,3 +20,63 @@ func TestFromContext(t *testing.T) {
 	loggerFromContext := FromContext(ctx)
 	assert.Equal(t, logger, loggerFromContext, "Logger retrieved from context should be the same one we placed in the context")
 }
+
+func TestLogCtxWithTrace(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New("logger-tester")
+	logger.SetOutput(buf)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	logger.InfoCtx(ctx, "testloginfo", M{"key1": "val1"})
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog("logger-tester", kv.Info, "testloginfo", M{
+		"key1":     "val1",
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":  "00f067aa0ba902b7",
+	}))
+
+	t.Log("no span in context")
+	buf.Reset()
+	logger.WarnCtx(context.Background(), "testlogwarning", nil)
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog("logger-tester", kv.Warning, "testlogwarning", M{}))
+}
+
+func TestRegisterContextExtractor(t *testing.T) {
+	type requestKey struct{}
+	RegisterContextExtractor("test-request", func(ctx context.Context) M {
+		if id, ok := ctx.Value(requestKey{}).(string); ok {
+			return M{"request_id": id, "extra": "from-extractor"}
+		}
+		return nil
+	})
+	defer UnregisterContextExtractor("test-request")
+
+	buf := &bytes.Buffer{}
+	logger := New("logger-tester")
+	logger.SetOutput(buf)
+	ctx := context.WithValue(context.Background(), requestKey{}, "r1")
+
+	logger.ErrorCtx(ctx, "testlogerror", M{"extra": "from-data"})
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog("logger-tester", kv.Error, "testlogerror", M{
+		"request_id": "r1",
+		"extra":      "from-data",
+	}))
+
+	t.Log("filtered lines are not extracted")
+	buf.Reset()
+	logger.SetLogLevel(Critical)
+	logger.ErrorCtx(ctx, "testlogerror", nil)
+	assert.Equal(t, "", buf.String())
+
+	t.Log("unregistered extractors are not run")
+	UnregisterContextExtractor("test-request")
+	buf.Reset()
+	logger.CriticalCtx(ctx, "testlogcritical", nil)
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog("logger-tester", kv.Critical, "testlogcritical", M{}))
+}
@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Field names used by ErrorE and CriticalE. They replace fields of the same name in the data
+// logged with an error.
+const (
+	// ErrorKey holds the error message.
+	ErrorKey = "error"
+	// ErrorTypeKey holds the concrete type of the error, e.g. "*fs.PathError".
+	ErrorTypeKey = "error_type"
+	// ErrorChainKey holds the errors wrapped by the error, found with errors.Unwrap and
+	// errors.Join, in depth-first order. Each has an ErrorKey and an ErrorTypeKey.
+	ErrorChainKey = "error_chain"
+	// ErrorStackKey holds a stack trace, either carried by an error in the chain or captured
+	// where the error was logged (see SetErrorStackTraces).
+	ErrorStackKey = "error_stack"
+)
+
+// maxErrorChain bounds the number of wrapped errors recorded in an error chain.
+const maxErrorChain = 32
+
+// SetErrorStackTraces sets whether ErrorE and CriticalE capture a stack trace at the call site
+// when none of the errors logged carries one. It is shared with child loggers created via With.
+func (l *Logger) SetErrorStackTraces(enabled bool) {
+	l.errorStacks.Store(enabled)
+}
+
+// ErrorE implements the method for the KayveeLogger interface.
+func (l *Logger) ErrorE(title string, err error, data map[string]interface{}) {
+	l.logError(Error, title, err, data)
+}
+
+// CriticalE implements the method for the KayveeLogger interface.
+func (l *Logger) CriticalE(title string, err error, data map[string]interface{}) {
+	l.logError(Critical, title, err, data)
+}
+
+func (l *Logger) logError(logLvl LogLevel, title string, err error, data map[string]interface{}) {
+	if logLvl < l.minLevel(title, data) {
+		return
+	}
+	fields := make(map[string]interface{}, len(data)+5)
+	for k, v := range data {
+		fields[k] = v
+	}
+	fields["title"] = title
+	if err != nil {
+		fields[ErrorKey] = err.Error()
+		fields[ErrorTypeKey] = errorType(err)
+		stack := errorStack(err)
+		if chain := errorChain(err); len(chain) > 0 {
+			fields[ErrorChainKey] = chain
+			for i := 0; stack == "" && i < len(chain); i++ {
+				stack = chain[i][ErrorStackKey].(string)
+			}
+			for _, e := range chain {
+				delete(e, ErrorStackKey)
+			}
+		}
+		if stack == "" && l.errorStacks.Load() {
+			stack = l.captureStack()
+		}
+		if stack != "" {
+			fields[ErrorStackKey] = stack
+		}
+	}
+	l.logChecked(logLvl, fields)
+}
+
+// errorChain returns the errors wrapped by err, depth first. Each entry also has the stack
+// carried by that error under ErrorStackKey, "" if none, for logError to pick from.
+func errorChain(err error) []map[string]interface{} {
+	chain := []map[string]interface{}{}
+	var walk func(err error)
+	walk = func(err error) {
+		var wrapped []error
+		switch e := err.(type) {
+		case interface{ Unwrap() error }:
+			wrapped = []error{e.Unwrap()}
+		case interface{ Unwrap() []error }:
+			wrapped = e.Unwrap()
+		}
+		for _, w := range wrapped {
+			if w == nil || len(chain) >= maxErrorChain {
+				continue
+			}
+			chain = append(chain, map[string]interface{}{
+				ErrorKey:      w.Error(),
+				ErrorTypeKey:  errorType(w),
+				ErrorStackKey: errorStack(w),
+			})
+			walk(w)
+		}
+	}
+	walk(err)
+	return chain
+}
+
+func errorType(err error) string {
+	return reflect.TypeOf(err).String()
+}
+
+// errorStack returns the stack trace carried by err, if it has a StackTrace method taking no
+// arguments, like the errors created by github.com/pkg/errors or github.com/go-errors/errors.
+func errorStack(err error) string {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%+v", method.Call(nil)[0].Interface()))
+}
+
+// captureStack returns the stack of the caller, with one "function\n\tfile:line" entry per
+// frame. Like the caller annotation, it starts at the first frame outside of kayvee's logger
+// packages, skipping l.callerSkip more frames after it.
+func (l *Logger) captureStack() string {
+	pcs := make([]uintptr, 64)
+	// skip runtime.Callers and captureStack
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	skip := l.callerSkip
+	started := false
+	var sb strings.Builder
+	for {
+		frame, more := frames.Next()
+		if !started && !isLoggerFrame(frame) {
+			if skip == 0 {
+				started = true
+			} else {
+				skip--
+			}
+		}
+		if started {
+			fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
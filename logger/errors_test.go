@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stackError carries a stack trace, like the errors created by github.com/pkg/errors.
+type stackError struct {
+	msg   string
+	stack string
+}
+
+func (e *stackError) Error() string      { return e.msg }
+func (e *stackError) StackTrace() string { return e.stack }
+
+func TestErrorE(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+
+	_, pathErr := os.Open("/does/not/exist")
+	err := fmt.Errorf("loading config: %w", pathErr)
+	data := M{"key1": "val1"}
+	lg.ErrorE("load-failed", err, data)
+	assert.Equal(t, M{"key1": "val1"}, data, "data should not be modified")
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 1)
+	line := lines[0]
+	assert.Equal(t, "error", line["level"])
+	assert.Equal(t, "load-failed", line["title"])
+	assert.Equal(t, "val1", line["key1"])
+	assert.Equal(t, "loading config: open /does/not/exist: no such file or directory", line[ErrorKey])
+	assert.Equal(t, "*fmt.wrapError", line[ErrorTypeKey])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			ErrorKey:     "open /does/not/exist: no such file or directory",
+			ErrorTypeKey: "*fs.PathError",
+		},
+		map[string]interface{}{
+			ErrorKey:     "no such file or directory",
+			ErrorTypeKey: "syscall.Errno",
+		},
+	}, line[ErrorChainKey])
+	assert.NotContains(t, line, ErrorStackKey)
+}
+
+func TestCriticalEJoinedErrors(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+
+	err := errors.Join(errors.New("first"), fmt.Errorf("second: %w", errors.New("cause")))
+	lg.CriticalE("shutdown-failed", err, nil)
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "critical", lines[0]["level"])
+	assert.Equal(t, "*errors.joinError", lines[0][ErrorTypeKey])
+	chain := lines[0][ErrorChainKey].([]interface{})
+	messages := []string{}
+	for _, e := range chain {
+		messages = append(messages, e.(map[string]interface{})[ErrorKey].(string))
+	}
+	assert.Equal(t, []string{"first", "second: cause", "cause"}, messages)
+}
+
+func TestErrorEStackTraces(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+
+	t.Log("stacks are taken from errors that carry one")
+	lg.ErrorE("carried", fmt.Errorf("wrapped: %w", &stackError{"inner", "main.main\n\tmain.go:10"}), nil)
+	line := decodeLines(t, buf)[0]
+	assert.Equal(t, "main.main\n\tmain.go:10", line[ErrorStackKey])
+	assert.NotContains(t, line[ErrorChainKey].([]interface{})[0], ErrorStackKey)
+
+	t.Log("stacks are only captured at the call site when enabled")
+	buf.Reset()
+	lg.ErrorE("not-captured", errors.New("plain"), nil)
+	assert.NotContains(t, decodeLines(t, buf)[0], ErrorStackKey)
+
+	buf.Reset()
+	lg.SetErrorStackTraces(true)
+	lg.With(M{"a": "b"}).ErrorE("captured", errors.New("plain"), nil)
+	stack, ok := decodeLines(t, buf)[0][ErrorStackKey].(string)
+	require.True(t, ok)
+	assert.Regexp(t, `^github.com/Clever/kayvee-go/v7/logger.TestErrorEStackTraces\n\t.*errors_test.go:\d+`, stack)
+
+	t.Log("captured stacks start at the caller of wrappers")
+	buf.Reset()
+	(&closeRecorder{KayveeLogger: lg}).ErrorE("captured", errors.New("plain"), nil)
+	stack, _ = decodeLines(t, buf)[0][ErrorStackKey].(string)
+	assert.Regexp(t, `^github.com/Clever/kayvee-go/v7/logger.TestErrorEStackTraces\n`, stack)
+
+	buf.Reset()
+	func() {
+		lg.WithCallerSkip(1).ErrorE("captured", errors.New("plain"), nil)
+	}()
+	stack, _ = decodeLines(t, buf)[0][ErrorStackKey].(string)
+	assert.Regexp(t, `^github.com/Clever/kayvee-go/v7/logger.TestErrorEStackTraces\n`, stack)
+}
+
+func TestErrorEReplacesErrorField(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	lg.ErrorE("failed", errors.New("from err"), M{ErrorKey: "from data"})
+	lg.ErrorE("failed", nil, M{ErrorKey: "from data"})
+	lines := decodeLines(t, buf)
+	assert.Equal(t, "from err", lines[0][ErrorKey])
+	assert.Equal(t, "from data", lines[1][ErrorKey])
+}
+
+func TestErrorENilAndLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+
+	lg.ErrorE("no-error", nil, M{"key1": "val1"})
+	line := decodeLines(t, buf)[0]
+	assert.Equal(t, "val1", line["key1"])
+	assert.NotContains(t, line, ErrorKey)
+
+	buf.Reset()
+	lg.SetLogLevel(Critical)
+	lg.ErrorE("filtered", errors.New("e"), nil)
+	assert.Empty(t, buf.String())
+}
+
+func TestErrorEMockLogger(t *testing.T) {
+	mockLogger := NewMockCountLogger("testing")
+	mockLogger.ErrorE("e", errors.New("e"), nil)
+	mockLogger.CriticalE("c", errors.New("c"), M{})
+	assert.Equal(t, map[string]int{}, mockLogger.RuleCounts())
+}
@@ -0,0 +1,200 @@
+package logger
+
+import (
+	"math"
+	"reflect"
+	"sync"
+	"time"
+
+	kv "github.com/Clever/kayvee-go/v7"
+)
+
+// Field is a typed key and value passed to the logging methods that take a title, e.g.
+//
+//	lg.Info("request-finished", logger.String("path", path), logger.Int("status", 200), logger.Err(err))
+//
+// logs the same line, and routes it the same way, as
+//
+//	lg.InfoD("request-finished", logger.M{"path": path, "status": 200, "error": err.Error()})
+//
+// but does not allocate if the line is filtered out by the log level. When nothing needs the
+// line as a map, i.e. there is no router, redaction policy or sampling and the formatter is
+// kv.Format, the fields are encoded straight to the output without building one. Calls on a
+// *Logger, rather than through the KayveeLogger interface, also avoid allocating the slice of
+// fields.
+type Field = kv.Field
+
+// String returns a Field with a string value.
+func String(key, val string) Field {
+	return Field{Key: key, Type: kv.StringType, String: val}
+}
+
+// Int returns a Field with an int value.
+func Int(key string, val int) Field {
+	return Field{Key: key, Type: kv.IntType, Integer: int64(val)}
+}
+
+// Int64 returns a Field with an int64 value.
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Type: kv.IntType, Integer: val}
+}
+
+// Float64 returns a Field with a float64 value.
+func Float64(key string, val float64) Field {
+	return Field{Key: key, Type: kv.FloatType, Integer: int64(math.Float64bits(val))}
+}
+
+// Bool returns a Field with a bool value.
+func Bool(key string, val bool) Field {
+	var i int64
+	if val {
+		i = 1
+	}
+	return Field{Key: key, Type: kv.BoolType, Integer: i}
+}
+
+// Duration returns a Field with a time.Duration value.
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, Type: kv.DurationType, Integer: int64(val)}
+}
+
+// Err returns a Field with the message of err under ErrorKey. If err is nil, the field is left
+// out. Use ErrorE to also log the error's type, wrapped errors and stack trace.
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: ErrorKey, Type: kv.SkipType}
+	}
+	return Field{Key: ErrorKey, Type: kv.ErrorType, Interface: err}
+}
+
+// Any returns a Field with a value of any type, formatted like a value in an M. Unlike the typed
+// constructors, boxing val in an interface may allocate.
+func Any(key string, val interface{}) Field {
+	return Field{Key: key, Type: kv.AnyType, Interface: val}
+}
+
+// fieldsPool holds the slices used to collect the fields of a line.
+var fieldsPool = sync.Pool{
+	New: func() interface{} { return &lineFields{} },
+}
+
+type lineFields struct {
+	fields []Field
+	line   []byte
+}
+
+// logFields logs a line with typed fields. If the line is not filtered out, it is either
+// encoded straight from the fields or, if something needs it as a map, logged like the D
+// methods.
+func (l *Logger) logFields(logLvl LogLevel, title string, fields []Field) {
+	source, hasSource := fieldsSource(fields)
+	if logLvl < l.minLevelFor(title, source, hasSource) {
+		return
+	}
+	s := l.settings.Load()
+	fl, ok := s.fLogger.(*defaultFormatLogger)
+	if !ok || !fl.kvFormat || s.logRouter != nil || globalRouter.Load() != nil || s.sampler != nil ||
+		l.redactionPolicy() != nil {
+		data := make(M, len(fields)+1)
+		for _, f := range fields {
+			if f.Type != kv.SkipType {
+				data[f.Key] = f.Value()
+			}
+		}
+		data["title"] = title
+		l.logChecked(logLvl, data)
+		return
+	}
+
+	lf := fieldsPool.Get().(*lineFields)
+	l.globalsL.RLock()
+	for k, v := range l.globals {
+		lf.fields = append(lf.fields, Field{Key: k, Type: kv.AnyType, Interface: v})
+	}
+	l.globalsL.RUnlock()
+	// as with the D methods, the fields override the globals, and the title and level
+	// override the fields
+	lf.fields = append(lf.fields, fields...)
+	lf.fields = append(lf.fields, String("title", title), String("level", logLvl.String()))
+	lf.fields = l.appendTimestampField(lf.fields)
+	lf.fields = l.appendCallerFields(lf.fields)
+
+	lf.line = kv.AppendFields(lf.line[:0], lf.fields)
+	fl.write(string(lf.line))
+
+	clear(lf.fields)
+	lf.fields = lf.fields[:0]
+	if cap(lf.line) <= maxPooledLineSize {
+		fieldsPool.Put(lf)
+	}
+}
+
+// maxPooledLineSize bounds the line buffers kept for reuse.
+const maxPooledLineSize = 64 << 10
+
+// fieldsSource returns the value of the last string field named "source", if there is one.
+func fieldsSource(fields []Field) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key != "source" {
+			continue
+		}
+		switch fields[i].Type {
+		case kv.SkipType:
+			continue
+		case kv.StringType:
+			return fields[i].String, true
+		case kv.AnyType:
+			source, ok := fields[i].Interface.(string)
+			return source, ok
+		}
+		return "", false
+	}
+	return "", false
+}
+
+func hasField(fields []Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key && f.Type != kv.SkipType {
+			return true
+		}
+	}
+	return false
+}
+
+// appendTimestampField is addTimestamp for a line of fields.
+func (l *Logger) appendTimestampField(fields []Field) []Field {
+	format := l.TimestampFormat()
+	if format == NoTimestamp || hasField(fields, TimestampKey) {
+		return fields
+	}
+	now := l.now()
+	switch format {
+	case TimestampRFC3339Nano:
+		fields = append(fields, String(TimestampKey, now.UTC().Format(time.RFC3339Nano)))
+	case TimestampEpochMillis:
+		fields = append(fields, Int64(TimestampKey, now.UnixMilli()))
+	}
+	return fields
+}
+
+// appendCallerFields is addCaller for a line of fields.
+func (l *Logger) appendCallerFields(fields []Field) []Field {
+	annotation := CallerAnnotation(l.callerAnnotation.Load())
+	if annotation == NoCaller || hasField(fields, CallerKey) {
+		return fields
+	}
+	frame, ok := l.callerFrame()
+	if !ok {
+		return fields
+	}
+	fields = append(fields, String(CallerKey, callerLocation(frame)))
+	if annotation == CallerLineAndFunc {
+		fields = append(fields, String(FuncKey, callerFunc(frame)))
+	}
+	return fields
+}
+
+// isKayveeFormat returns whether formatter is kv.Format, which AppendFields encodes like.
+func isKayveeFormat(formatter Formatter) bool {
+	return formatter != nil && reflect.ValueOf(formatter).Pointer() == reflect.ValueOf(kv.Format).Pointer()
+}
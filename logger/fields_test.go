@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/Clever/kayvee-go/v7/redact"
+	"github.com/Clever/kayvee-go/v7/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldsMatchMapMethods(t *testing.T) {
+	testRouter, err := router.NewFromRoutes(map[string]router.Rule{
+		"by-path": {
+			Matchers: router.RuleMatchers{"path": []string{"/students"}},
+			Output:   router.RuleOutput{"type": "analytics", "series": "s", "status": "%{status}"},
+		},
+		"by-flag": {
+			Matchers: router.RuleMatchers{"ok": []string{"true"}},
+			Output:   router.RuleOutput{"type": "notifications", "channel": "#c", "icon": ":x:", "message": "m", "user": "u"},
+		},
+	})
+	require.NoError(t, err)
+	policy, err := redact.New([]redact.Rule{{Keys: []string{"path"}, Action: redact.Hash}})
+	require.NoError(t, err)
+
+	configs := map[string]func(lg *Logger){
+		"default": func(lg *Logger) {},
+		"timestamp and caller": func(lg *Logger) {
+			lg.SetClock(func() time.Time { return time.UnixMilli(1760590000123) })
+			lg.SetTimestampFormat(TimestampRFC3339Nano)
+			lg.SetCallerAnnotation(CallerLineAndFunc)
+		},
+		"router":    func(lg *Logger) { lg.SetRouter(testRouter) },
+		"redaction": func(lg *Logger) { lg.SetRedactionPolicy(policy) },
+		"pretty": func(lg *Logger) {
+			lg.SetClock(func() time.Time { return time.UnixMilli(1760590000123) })
+			lg.SetTimestampFormat(TimestampEpochMillis)
+			lg.SetFormatter(NewPrettyFormatter(false))
+		},
+		"logfmt": func(lg *Logger) { lg.SetFormatter(kv.FormatLogfmt) },
+		"level overrides": func(lg *Logger) {
+			require.NoError(t, lg.SetLevelOverrides(LevelOverrides{Sources: map[string]LogLevel{"other": Error}}))
+		},
+	}
+	err = errors.New("it <broke>")
+	for name, configure := range configs {
+		t.Run(name, func(t *testing.T) {
+			fieldsBuf, mapBuf := &bytes.Buffer{}, &bytes.Buffer{}
+			fieldsLg := NewConcreteLoggerWithContext("logger-tester", M{"global": "g", "path": "overridden"}).WithCallerSkip(1)
+			fieldsLg.SetOutput(fieldsBuf)
+			configure(fieldsLg)
+			mapLg := NewConcreteLoggerWithContext("logger-tester", M{"global": "g", "path": "overridden"}).WithCallerSkip(1)
+			mapLg.SetOutput(mapBuf)
+			configure(mapLg)
+
+			// with a caller skip of 1, both loggers report the line in `at` as the caller
+			at := func(log func()) { log() }
+			at(func() {
+				fieldsLg.Info("request", String("path", "/students"), Int("status", 200),
+					Int64("big", math.MaxInt64), Float64("ms", 1.25), Bool("ok", true),
+					Duration("d", time.Second), Err(err), Any("ids", []string{"a"}))
+			})
+			at(func() {
+				mapLg.InfoD("request", M{"path": "/students", "status": 200,
+					"big": int64(math.MaxInt64), "ms": 1.25, "ok": true,
+					"d": time.Second, "error": err.Error(), "ids": []string{"a"}})
+			})
+			at(func() { fieldsLg.Warn("skipped-error", Err(nil), Float64("nan", math.NaN())) })
+			at(func() { mapLg.WarnD("skipped-error", M{"nan": math.NaN()}) })
+			at(func() {
+				fieldsLg.Error("dup", String("k", "first"), String("k", "second"),
+					String("title", "ignored"), String("level", "ignored"))
+			})
+			at(func() { mapLg.ErrorD("dup", M{"k": "second"}) })
+			at(func() { fieldsLg.Critical("source", String("source", "other")) })
+			at(func() { mapLg.CriticalD("source", M{"source": "other"}) })
+			at(func() { fieldsLg.Info("source", String("source", "other")) })
+			at(func() { mapLg.InfoD("source", M{"source": "other"}) })
+			at(func() { fieldsLg.Trace("none") })
+			at(func() { mapLg.TraceD("none", M{}) })
+			at(func() { fieldsLg.Debug("none") })
+			at(func() { mapLg.DebugD("none", M{}) })
+
+			assert.NotEmpty(t, mapBuf.String())
+			assert.Equal(t, mapBuf.String(), fieldsBuf.String())
+		})
+	}
+}
+
+func TestFieldsOrder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	lg.Info("hello", String("b", "2"), Int("a", 1), String("type", "gauge"), Float64("value", 3))
+	assert.Equal(t, `{"level":"info","source":"logger-tester","title":"hello","type":"gauge","value":3,`+
+		`"a":1,"b":"2","deploy_env":"testing","wf_id":"abc123"}`+"\n", buf.String())
+}
+
+func TestFieldsNoAllocsWhenFiltered(t *testing.T) {
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(io.Discard)
+	lg.SetLogLevel(Warning)
+	err := errors.New("failed")
+	allocs := testing.AllocsPerRun(100, func() {
+		lg.Info("filtered", String("k", "v"), Int("n", 3), Float64("f", 1.5), Bool("b", true), Err(err))
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+// raceEnabled is set in race builds, where sync.Pool drops items at random.
+var raceEnabled = false
+
+func TestFieldsAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocations aren't stable with the race detector")
+	}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(io.Discard)
+	err := errors.New("failed")
+	fieldsAllocs := testing.AllocsPerRun(100, func() {
+		lg.Info("logged", String("k", "v"), Int("n", 3), Float64("f", 1.5), Bool("b", true), Err(err))
+	})
+	mapAllocs := testing.AllocsPerRun(100, func() {
+		lg.InfoD("logged", M{"k": "v", "n": 3, "f": 1.5, "b": true, "error": err.Error()})
+	})
+	assert.Less(t, fieldsAllocs, mapAllocs)
+	assert.LessOrEqual(t, fieldsAllocs, 3.0)
+}
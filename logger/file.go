@@ -0,0 +1,408 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp in the name of rotated files. It sorts lexically and
+// contains no characters that are invalid in file names.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+const compressSuffix = ".gz"
+
+// rotateRetryInterval is how long a writer whose rotation failed waits before trying again.
+const rotateRetryInterval = time.Minute
+
+// FileConfig configures a FileWriter. See NewFileWriter.
+type FileConfig struct {
+	// Filename is the file that lines are written to. Its directory is created if needed.
+	Filename string
+	// MaxSize is the size in bytes after which the file is rotated. Zero means no limit.
+	MaxSize int64
+	// RotateEvery is how long a file is written to before it is rotated. Zero means it is only
+	// rotated by size.
+	RotateEvery time.Duration
+	// MaxBackups is the number of rotated files kept. Zero keeps all of them, subject to MaxAge.
+	MaxBackups int
+	// MaxAge is how long rotated files are kept. Zero keeps them regardless of age.
+	MaxAge time.Duration
+	// Compress gzips rotated files in the background.
+	Compress bool
+}
+
+// FileWriter is an io.Writer that appends log lines to a file, rotating it by size and age,
+// e.g. for hosts without a log collector:
+//
+//	fw, err := logger.NewFileWriter(logger.FileConfig{
+//		Filename:   "/var/log/myjob/myjob.log",
+//		MaxSize:    100 << 20,
+//		MaxBackups: 5,
+//		Compress:   true,
+//	})
+//	...
+//	defer fw.Close()
+//	lg.SetOutput(fw)
+//
+// A rotated file is renamed to `<name>-<timestamp><ext>` next to Filename. Rotation, including
+// by Rotate and Reopen, happens between lines, so a line is never split across files. FileWriter is safe for concurrent use.
+type FileWriter struct {
+	config FileConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	// partial is whether the last write didn't end with a newline, in which case rotation
+	// waits for the rest of the line.
+	partial bool
+	// rotatePending and reopenPending are whether Rotate or Reopen was called while partial
+	// was set, and is due once the line has been finished.
+	rotatePending bool
+	reopenPending bool
+	// retryRotateAt is when a rotation that failed is tried again.
+	retryRotateAt time.Time
+	closed        bool
+
+	millCh chan struct{}
+	millWG sync.WaitGroup
+}
+
+// NewFileWriter opens (or creates) config.Filename for appending.
+func NewFileWriter(config FileConfig) (*FileWriter, error) {
+	if config.Filename == "" {
+		return nil, errors.New("file output has no filename")
+	}
+	w := &FileWriter{
+		config: config,
+		now:    time.Now,
+		millCh: make(chan struct{}, 1),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.millWG.Add(1)
+	go w.mill()
+	return w, nil
+}
+
+// Write implements io.Writer, rotating the file first if it is due.
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	if !w.partial && w.shouldRotate(int64(len(p))) {
+		// a failed rotation leaves the current file open, so keep writing to it and try again
+		// later rather than losing lines
+		if err := w.rotate(); err != nil {
+			log.Printf("WARN: kayvee file output can't rotate log file: %s", err)
+			w.retryRotateAt = w.now().Add(rotateRetryInterval)
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if n > 0 {
+		w.partial = p[n-1] != '\n'
+	}
+	if !w.partial {
+		w.runPending()
+	}
+	return n, err
+}
+
+// Rotate rotates the file now, regardless of its size and age. If the last write didn't end
+// with a newline, the file is rotated once the line has been finished instead, and any error
+// rotating it is logged to the standard logger.
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.partial {
+		w.rotatePending = true
+		return nil
+	}
+	return w.rotate()
+}
+
+// Reopen opens Filename again and closes the file written so far, e.g. after an external tool
+// such as logrotate has moved it. See NotifyReopen. If Filename can't be opened, lines keep
+// being written to the current file. Like Rotate, it waits for a partly written line to be
+// finished.
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.partial {
+		w.reopenPending = true
+		return nil
+	}
+	return w.reopen()
+}
+
+// Close closes the file and waits for rotated files to be compressed and cleaned up.
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	err := w.file.Close()
+	close(w.millCh)
+	w.mu.Unlock()
+
+	w.millWG.Wait()
+	return err
+}
+
+// runPending rotates or reopens the file if Rotate or Reopen was called while a line was
+// partly written. w.mu must be held.
+func (w *FileWriter) runPending() {
+	switch {
+	case w.rotatePending:
+		// rotating opens Filename again too, so it covers a pending reopen
+		if err := w.rotate(); err != nil {
+			log.Printf("WARN: kayvee file output can't rotate log file: %s", err)
+		}
+	case w.reopenPending:
+		if err := w.reopen(); err != nil {
+			log.Printf("WARN: kayvee file output can't reopen log file: %s", err)
+		}
+	}
+	w.rotatePending = false
+	w.reopenPending = false
+}
+
+// shouldRotate returns whether the file should be rotated before writing n more bytes.
+func (w *FileWriter) shouldRotate(n int64) bool {
+	if !w.retryRotateAt.IsZero() && w.now().Before(w.retryRotateAt) {
+		return false
+	}
+	if w.config.MaxSize > 0 && w.size > 0 && w.size+n > w.config.MaxSize {
+		return true
+	}
+	return w.config.RotateEvery > 0 && w.now().Sub(w.openedAt) >= w.config.RotateEvery
+}
+
+// open opens Filename for appending. w.mu must be held.
+func (w *FileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.config.Filename), 0755); err != nil {
+		return fmt.Errorf("can't create directory for log file: %s", err)
+	}
+	f, err := os.OpenFile(w.config.Filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("can't open log file: %s", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("can't stat log file: %s", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	w.openedAt = w.now()
+	w.partial = false
+	w.retryRotateAt = time.Time{}
+	return nil
+}
+
+// reopen opens Filename again and closes the current file. w.mu must be held.
+func (w *FileWriter) reopen() error {
+	old := w.file
+	if err := w.open(); err != nil {
+		return err
+	}
+	return old.Close()
+}
+
+// rotate renames the file to a backup and opens a new one. The current file is only closed
+// once the new one is open, so that a failed rotation leaves the writer usable. w.mu must be
+// held.
+func (w *FileWriter) rotate() error {
+	// don't overwrite a file rotated in the same millisecond
+	rotatedAt := w.now()
+	for {
+		name := w.backupName(rotatedAt)
+		if !fileExists(name) && !fileExists(name+compressSuffix) {
+			break
+		}
+		rotatedAt = rotatedAt.Add(time.Millisecond)
+	}
+	if err := os.Rename(w.config.Filename, w.backupName(rotatedAt)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't rotate log file: %s", err)
+	}
+	old := w.file
+	if err := w.open(); err != nil {
+		return err
+	}
+	if err := old.Close(); err != nil {
+		log.Printf("WARN: kayvee file output can't close rotated file: %s", err)
+	}
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+		// the mill is already due to run
+	}
+	return nil
+}
+
+// backupName returns the name a file rotated at `t` is renamed to.
+func (w *FileWriter) backupName(t time.Time) string {
+	prefix, ext := w.backupPrefixAndExt()
+	return prefix + t.UTC().Format(backupTimeFormat) + ext
+}
+
+func (w *FileWriter) backupPrefixAndExt() (string, string) {
+	ext := filepath.Ext(w.config.Filename)
+	return strings.TrimSuffix(w.config.Filename, ext) + "-", ext
+}
+
+// mill compresses and removes rotated files after each rotation, until the writer is closed.
+func (w *FileWriter) mill() {
+	defer w.millWG.Done()
+	for range w.millCh {
+		w.millOnce()
+	}
+}
+
+// logBackup is a rotated file and the time it was rotated.
+type logBackup struct {
+	path       string
+	rotatedAt  time.Time
+	compressed bool
+}
+
+func (w *FileWriter) millOnce() {
+	backups, err := w.backups()
+	if err != nil {
+		log.Printf("WARN: kayvee file output can't list rotated files: %s", err)
+		return
+	}
+
+	var remove []logBackup
+	if w.config.MaxBackups > 0 && len(backups) > w.config.MaxBackups {
+		remove = backups[w.config.MaxBackups:]
+		backups = backups[:w.config.MaxBackups]
+	}
+	if w.config.MaxAge > 0 {
+		cutoff := w.now().Add(-w.config.MaxAge)
+		kept := backups[:0]
+		for _, b := range backups {
+			if b.rotatedAt.Before(cutoff) {
+				remove = append(remove, b)
+			} else {
+				kept = append(kept, b)
+			}
+		}
+		backups = kept
+	}
+
+	for _, b := range remove {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("WARN: kayvee file output can't remove rotated file: %s", err)
+		}
+	}
+	if !w.config.Compress {
+		return
+	}
+	for _, b := range backups {
+		if b.compressed {
+			continue
+		}
+		if err := compressFile(b.path); err != nil {
+			log.Printf("WARN: kayvee file output can't compress rotated file: %s", err)
+		}
+	}
+}
+
+// backups returns the rotated files of the writer, newest first.
+func (w *FileWriter) backups() ([]logBackup, error) {
+	entries, err := os.ReadDir(filepath.Dir(w.config.Filename))
+	if err != nil {
+		return nil, err
+	}
+	prefix, ext := w.backupPrefixAndExt()
+	prefix = filepath.Base(prefix)
+
+	backups := []logBackup{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		compressed := strings.HasSuffix(name, ext+compressSuffix)
+		if compressed {
+			name = strings.TrimSuffix(name, compressSuffix)
+		}
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		t, err := time.Parse(backupTimeFormat, ts)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{
+			path:       filepath.Join(filepath.Dir(w.config.Filename), e.Name()),
+			rotatedAt:  t,
+			compressed: compressed,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].rotatedAt.After(backups[j].rotatedAt)
+	})
+	return backups, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compressFile gzips the file at `path` to `path`.gz and removes it.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + compressSuffix + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+compressSuffix)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
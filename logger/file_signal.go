@@ -0,0 +1,39 @@
+//go:build !windows
+
+package logger
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// NotifyReopen makes the writer reopen its file on SIGHUP, which external tools such as
+// logrotate send after moving it. Call the returned function to stop handling the signal; it
+// can safely be called more than once.
+func (w *FileWriter) NotifyReopen() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-sigs:
+				if err := w.Reopen(); err != nil {
+					log.Printf("WARN: kayvee file output can't reopen log file: %s", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileWriterNotifyReopen(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	fw, err := NewFileWriter(FileConfig{Filename: filename})
+	require.NoError(t, err)
+	defer fw.Close()
+	stop := fw.NotifyReopen()
+	defer stop()
+
+	fmt.Fprintln(fw, "before")
+	require.NoError(t, os.Rename(filename, filepath.Join(dir, "app.log.1")))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filename)
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	stop()
+	assert.NotPanics(t, stop)
+}
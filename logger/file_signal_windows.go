@@ -0,0 +1,6 @@
+package logger
+
+// NotifyReopen is a no-op on Windows, which has no SIGHUP.
+func (w *FileWriter) NotifyReopen() (stop func()) {
+	return func() {}
+}
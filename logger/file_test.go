@@ -0,0 +1,242 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readLogFiles returns the lines of the log file and its backups in `dir`, decompressing the
+// gzipped ones, keyed by file name.
+func readLogFiles(t *testing.T, dir string) map[string][]string {
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	files := map[string][]string{}
+	for _, e := range entries {
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+		var r io.Reader = f
+		if strings.HasSuffix(e.Name(), ".gz") {
+			gz, err := gzip.NewReader(f)
+			require.NoError(t, err)
+			r = gz
+		}
+		b, err := io.ReadAll(r)
+		f.Close()
+		require.NoError(t, err)
+		files[e.Name()] = strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	}
+	return files
+}
+
+func TestFileWriterRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	fw, err := NewFileWriter(FileConfig{Filename: filepath.Join(dir, "app.log"), MaxSize: 300})
+	require.NoError(t, err)
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(fw)
+
+	for i := 0; i < 20; i++ {
+		lg.InfoD("line", M{"i": i})
+	}
+	require.NoError(t, fw.Close())
+
+	files := readLogFiles(t, dir)
+	assert.Greater(t, len(files), 1)
+	total := 0
+	for name, lines := range files {
+		info, err := os.Stat(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(300), name)
+		for _, line := range lines {
+			assert.True(t, strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}"), line)
+		}
+		total += len(lines)
+	}
+	assert.Equal(t, 20, total)
+}
+
+func TestFileWriterRotatesByTime(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fw := &FileWriter{
+		config: FileConfig{Filename: filepath.Join(dir, "app.log"), RotateEvery: time.Hour},
+		now:    func() time.Time { return now },
+		millCh: make(chan struct{}, 1),
+	}
+	require.NoError(t, fw.open())
+	fw.millWG.Add(1)
+	go fw.mill()
+
+	fmt.Fprintln(fw, "first")
+	now = now.Add(30 * time.Minute)
+	fmt.Fprintln(fw, "second")
+	now = now.Add(30 * time.Minute)
+	fmt.Fprintln(fw, "third")
+	require.NoError(t, fw.Close())
+
+	assert.Equal(t, map[string][]string{
+		"app.log":                         {"third"},
+		"app-2024-01-01T01-00-00.000.log": {"first", "second"},
+	}, readLogFiles(t, dir))
+}
+
+func TestFileWriterRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fw := &FileWriter{
+		config: FileConfig{
+			Filename:   filepath.Join(dir, "app.log"),
+			MaxBackups: 3,
+			MaxAge:     150 * time.Minute,
+			Compress:   true,
+		},
+		now:    func() time.Time { return now },
+		millCh: make(chan struct{}, 1),
+	}
+	require.NoError(t, fw.open())
+
+	// rotate without the background mill, then run it once for all of the backups
+	for i := 0; i < 4; i++ {
+		fmt.Fprintln(fw, i)
+		require.NoError(t, fw.Rotate())
+		now = now.Add(time.Hour)
+	}
+	fmt.Fprintln(fw, 4)
+	fw.millOnce()
+
+	// the backup rotated at 00:00 is beyond MaxBackups, and the one rotated at 01:00 is older
+	// than MaxAge
+	assert.Equal(t, map[string][]string{
+		"app.log":                            {"4"},
+		"app-2024-01-01T02-00-00.000.log.gz": {"2"},
+		"app-2024-01-01T03-00-00.000.log.gz": {"3"},
+	}, readLogFiles(t, dir))
+	require.NoError(t, fw.file.Close())
+}
+
+func TestFileWriterDoesNotSplitPartialLines(t *testing.T) {
+	dir := t.TempDir()
+	fw, err := NewFileWriter(FileConfig{Filename: filepath.Join(dir, "app.log"), MaxSize: 10})
+	require.NoError(t, err)
+
+	fmt.Fprint(fw, "0123456789")
+	fmt.Fprint(fw, "abc\n")
+	fmt.Fprint(fw, "next\n")
+	require.NoError(t, fw.Close())
+
+	files := readLogFiles(t, dir)
+	assert.Equal(t, []string{"next"}, files["app.log"])
+	delete(files, "app.log")
+	for _, lines := range files {
+		assert.Equal(t, []string{"0123456789abc"}, lines)
+	}
+}
+
+func TestFileWriterDefersRotateAndReopenUntilLineEnds(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	fw, err := NewFileWriter(FileConfig{Filename: filename})
+	require.NoError(t, err)
+	fw.now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	fmt.Fprint(fw, "rotated ")
+	require.NoError(t, fw.Rotate())
+	fmt.Fprint(fw, "line\n")
+	fmt.Fprint(fw, "moved ")
+	require.NoError(t, os.Rename(filename, filepath.Join(dir, "app.log.1")))
+	require.NoError(t, fw.Reopen())
+	fmt.Fprint(fw, "line\n")
+	fmt.Fprintln(fw, "after")
+	require.NoError(t, fw.Close())
+
+	assert.Equal(t, map[string][]string{
+		"app.log":                         {"after"},
+		"app.log.1":                       {"moved line"},
+		"app-2024-01-01T00-00-00.000.log": {"rotated line"},
+	}, readLogFiles(t, dir))
+}
+
+func TestFileWriterConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	fw, err := NewFileWriter(FileConfig{Filename: filepath.Join(dir, "app.log"), MaxSize: 1000})
+	require.NoError(t, err)
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(fw)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				lg.InfoD("line", M{"goroutine": g, "i": i})
+			}
+		}(g)
+	}
+	wg.Wait()
+	require.NoError(t, fw.Close())
+
+	total := 0
+	for _, lines := range readLogFiles(t, dir) {
+		for _, line := range lines {
+			assert.True(t, strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}"), line)
+		}
+		total += len(lines)
+	}
+	assert.Equal(t, 400, total)
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	fw, err := NewFileWriter(FileConfig{Filename: filename})
+	require.NoError(t, err)
+
+	fmt.Fprintln(fw, "before")
+	require.NoError(t, os.Rename(filename, filepath.Join(dir, "app.log.1")))
+	require.NoError(t, fw.Reopen())
+	fmt.Fprintln(fw, "after")
+	require.NoError(t, fw.Close())
+
+	assert.Equal(t, map[string][]string{
+		"app.log":   {"after"},
+		"app.log.1": {"before"},
+	}, readLogFiles(t, dir))
+	_, err = fw.Write([]byte("closed\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestFileWriterKeepsWritingWhenRotationFails(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	fw, err := NewFileWriter(FileConfig{Filename: filepath.Join(dir, "app.log"), MaxSize: 10})
+	require.NoError(t, err)
+	defer fw.Close()
+	fmt.Fprintln(fw, "first")
+
+	// replacing the directory with a file makes both renaming and opening the log file fail
+	// (unlike permissions, this also holds when the tests run as root)
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, os.WriteFile(dir, nil, 0644))
+	assert.Error(t, fw.Rotate())
+	assert.Error(t, fw.Reopen())
+	n, err := fmt.Fprintln(fw, "rotation is due")
+	assert.NoError(t, err, "the line is written to the current file")
+	assert.Equal(t, len("rotation is due\n"), n)
+
+	// once the directory is back, the writer recovers
+	require.NoError(t, os.Remove(dir))
+	require.NoError(t, fw.Reopen())
+	fmt.Fprintln(fw, "after")
+	require.NoError(t, fw.Close())
+	assert.Equal(t, map[string][]string{"app.log": {"after"}}, readLogFiles(t, dir))
+}
@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// decodeLines decodes each JSON line written to buf.
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	out := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var data map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &data))
+		out = append(out, data)
+	}
+	return out
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"io"
 
 	"github.com/Clever/kayvee-go/v7/router"
@@ -25,6 +26,11 @@ type KayveeLogger interface {
 	// GetContext reads a key-val from the global map of data that will be logged with all log messages.
 	GetContext(key string) (interface{}, bool)
 
+	// With returns a child logger that logs the given fields with all log messages. The child
+	// shares its output, formatter, log level and router with the parent, but has its own copy
+	// of the context, so AddContext on either logger does not affect the other.
+	With(fields M) KayveeLogger
+
 	// SetConfig allows configuration changes in one go
 	SetConfig(source string, logLvl LogLevel, formatter Formatter, output io.Writer)
 
@@ -54,30 +60,56 @@ type KayveeLogger interface {
 	// CounterD takes a string, value, and data map. It logs with LogLevel = Info
 	CounterD(title string, value int, data map[string]interface{})
 
-	// Critical takes a string and logs with LogLevel = Critical
-	Critical(title string)
+	// Critical takes a string and typed fields and logs with LogLevel = Critical
+	Critical(title string, fields ...Field)
 
 	// CriticalD takes a string and data map. It logs with LogLevel = Critical
 	CriticalD(title string, data map[string]interface{})
 
-	// Trace takes a string and logs with LogLevel = Trace
-	Trace(title string)
+	// CriticalE takes a string, error and data map. It logs with LogLevel = Critical and records
+	// the error's message, type, wrapped errors and stack trace. If err is not nil, these replace
+	// fields of the same name in data, e.g. "error".
+	CriticalE(title string, err error, data map[string]interface{})
+
+	// CriticalCtx takes a context, string and data map. It logs with LogLevel = Critical and adds the
+	// fields returned by the registered ContextExtractors, such as trace and span IDs.
+	CriticalCtx(ctx context.Context, title string, data map[string]interface{})
+
+	// Trace takes a string and typed fields and logs with LogLevel = Trace
+	Trace(title string, fields ...Field)
 
 	// TraceD takes a string and data map. It logs with LogLevel = Trace
 	TraceD(title string, data map[string]interface{})
 
-	// Debug takes a string and logs with LogLevel = Debug
-	Debug(title string)
+	// TraceCtx takes a context, string and data map. It logs with LogLevel = Trace and adds the
+	// fields returned by the registered ContextExtractors, such as trace and span IDs.
+	TraceCtx(ctx context.Context, title string, data map[string]interface{})
+
+	// Debug takes a string and typed fields and logs with LogLevel = Debug
+	Debug(title string, fields ...Field)
 
 	// DebugD takes a string and data map. It logs with LogLevel = Debug
 	DebugD(title string, data map[string]interface{})
 
-	// Error takes a string and logs with LogLevel = Error
-	Error(title string)
+	// DebugCtx takes a context, string and data map. It logs with LogLevel = Debug and adds the
+	// fields returned by the registered ContextExtractors, such as trace and span IDs.
+	DebugCtx(ctx context.Context, title string, data map[string]interface{})
+
+	// Error takes a string and typed fields and logs with LogLevel = Error
+	Error(title string, fields ...Field)
 
 	// ErrorD takes a string and data map. It logs with LogLevel = Error
 	ErrorD(title string, data map[string]interface{})
 
+	// ErrorE takes a string, error and data map. It logs with LogLevel = Error and records the
+	// error's message, type, wrapped errors and stack trace. If err is not nil, these replace
+	// fields of the same name in data, e.g. "error".
+	ErrorE(title string, err error, data map[string]interface{})
+
+	// ErrorCtx takes a context, string and data map. It logs with LogLevel = Error and adds the
+	// fields returned by the registered ContextExtractors, such as trace and span IDs.
+	ErrorCtx(ctx context.Context, title string, data map[string]interface{})
+
 	// GaugeFloat takes a string and float value. It logs with LogLevel = Info
 	GaugeFloat(title string, value float64)
 
@@ -90,21 +122,35 @@ type KayveeLogger interface {
 	// GaugeIntD takes a string, an integer value, and data map. It logs with LogLevel = Info
 	GaugeIntD(title string, value int, data map[string]interface{})
 
-	// Info takes a string and logs with LogLevel = Info
-	Info(title string)
+	// Histogram takes a string and float value. It logs with LogLevel = Info
+	Histogram(title string, value float64)
+
+	// HistogramD takes a string, a float value, and data map. It logs with LogLevel = Info
+	HistogramD(title string, value float64, data map[string]interface{})
+
+	// Info takes a string and typed fields and logs with LogLevel = Info
+	Info(title string, fields ...Field)
 
 	// InfoD takes a string and data map. It logs with LogLevel = Info
 	InfoD(title string, data map[string]interface{})
 
+	// InfoCtx takes a context, string and data map. It logs with LogLevel = Info and adds the
+	// fields returned by the registered ContextExtractors, such as trace and span IDs.
+	InfoCtx(ctx context.Context, title string, data map[string]interface{})
+
 	// Timer takes a string and logs with LogLevel = Debug
 	Timer(title string) *Timer
 
 	// TimerD takes a string and data map. It logs with LogLevel = Debug
 	TimerD(title string, data map[string]interface{}) *Timer
 
-	// Warn takes a string and logs with LogLevel = Warning
-	Warn(title string)
+	// Warn takes a string and typed fields and logs with LogLevel = Warning
+	Warn(title string, fields ...Field)
 
 	// WarnD takes a string and data map. It logs with LogLevel = Warning
 	WarnD(title string, data map[string]interface{})
+
+	// WarnCtx takes a context, string and data map. It logs with LogLevel = Warning and adds the
+	// fields returned by the registered ContextExtractors, such as trace and span IDs.
+	WarnCtx(ctx context.Context, title string, data map[string]interface{})
 }
@@ -12,6 +12,7 @@ import (
 
 	"github.com/Clever/kayvee-go/v7/logger/analytics"
 
+	kv "github.com/Clever/kayvee-go/v7"
 	"github.com/Clever/kayvee-go/v7/logger"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -80,6 +81,9 @@ type Config struct {
 	KinesisPutRecordBatchMaxTime time.Duration
 	// KinesisAPI defaults to an API object configured with Region, but can be overriden here.
 	KinesisAPI kinesisiface.KinesisAPI
+	// TimestampFormat records the time each event was logged, which is earlier than when its
+	// batch is sent, as a "timestamp" field. Defaults to KAYVEE_TIMESTAMP_FORMAT.
+	TimestampFormat logger.TimestampFormat
 	// ErrLogger is a logger used to make sure errors from goroutines still get surfaced. Defaults to basic logger.Logger
 	ErrLogger logger.KayveeLogger
 }
@@ -89,7 +93,12 @@ type Config struct {
 func New(c Config) (*Logger, error) {
 	l := logger.New(c.DBName)
 	ksl := &Logger{KayveeLogger: l}
+	// Write parses each line, so it must be JSON even when KAYVEE_FORMAT is pretty or logfmt
+	l.SetFormatter(kv.Format)
 	l.SetOutput(ksl)
+	if lg, ok := l.(*logger.Logger); ok && c.TimestampFormat != logger.NoTimestamp {
+		lg.SetTimestampFormat(c.TimestampFormat)
+	}
 	env, dbname, streamName := c.Environment, c.DBName, c.StreamName
 	if dbname != "" && streamName != "" {
 		return nil, errors.New("cannot specify both DBName and StreamName in logger config")
@@ -2,6 +2,7 @@ package kinesisstream
 
 import (
 	"testing"
+	"time"
 
 	"github.com/Clever/kayvee-go/v7/logger"
 	"github.com/aws/aws-sdk-go/aws"
@@ -38,6 +39,32 @@ func TestLogger(t *testing.T) {
 				l.InfoD("test-title", logger.M{"foo": "bar", "partition_key": "1"})
 			},
 		},
+		{
+			name: "keeps the timestamp as event time",
+			klc: Config{
+				Environment:     "testenv",
+				DBName:          "testdb",
+				TimestampFormat: logger.TimestampRFC3339Nano,
+			},
+			mockExpectations: func(mk *MockKinesisAPI) {
+				mk.EXPECT().PutRecords(&kinesis.PutRecordsInput{
+					StreamName: aws.String("testenv--testdb"),
+					Records: []*kinesis.PutRecordsRequestEntry{
+						{
+							Data: []byte(`{"foo":"bar","timestamp":"2025-10-16T04:46:40.000000123Z"}
+`),
+							PartitionKey: aws.String("1"),
+						},
+					},
+				}).Return(&kinesis.PutRecordsOutput{FailedRecordCount: aws.Int64(0)}, nil)
+			},
+			ops: func(l logger.KayveeLogger) {
+				l.(*Logger).KayveeLogger.(*logger.Logger).SetClock(func() time.Time {
+					return time.Unix(1760590000, 123)
+				})
+				l.InfoD("test-title", logger.M{"foo": "bar", "partition_key": "1"})
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LevelController holds a log level that can be changed at runtime, e.g. to raise verbosity
+// while debugging an incident without redeploying. Every logger subscribed to a controller via
+// SetLevelController filters lines using its level. The level is read and written atomically.
+type LevelController struct {
+	level atomic.Int32
+}
+
+// NewLevelController creates a LevelController with the log level `logLvl`.
+func NewLevelController(logLvl LogLevel) *LevelController {
+	c := &LevelController{}
+	c.SetLevel(logLvl)
+	return c
+}
+
+// Level returns the current log level.
+func (c *LevelController) Level() LogLevel {
+	return LogLevel(c.level.Load())
+}
+
+// SetLevel changes the log level of every logger subscribed to the controller.
+func (c *LevelController) SetLevel(logLvl LogLevel) {
+	c.level.Store(int32(logLvl))
+}
+
+// stepLevel moves the level by `delta`, staying within Trace and Critical.
+func (c *LevelController) stepLevel(delta int32) LogLevel {
+	for {
+		old := c.level.Load()
+		lvl := old + delta
+		if lvl < int32(Trace) {
+			lvl = int32(Trace)
+		} else if lvl > int32(Critical) {
+			lvl = int32(Critical)
+		}
+		if c.level.CompareAndSwap(old, lvl) {
+			return LogLevel(lvl)
+		}
+	}
+}
+
+type levelPayload struct {
+	Level string `json:"level"`
+}
+
+// ServeHTTP implements http.Handler. GET returns the current level as JSON, e.g.
+// `{"level":"info"}`, and PUT sets it from a body of the same form.
+func (c *LevelController) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req levelPayload
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeLevelError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+			return
+		}
+		logLvl, err := ParseLogLevel(req.Level)
+		if err != nil {
+			writeLevelError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		c.SetLevel(logLvl)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeLevelError(w, http.StatusMethodNotAllowed, "only GET and PUT are supported")
+		return
+	}
+	json.NewEncoder(w).Encode(levelPayload{Level: c.Level().String()})
+}
+
+func writeLevelError(w http.ResponseWriter, status int, msg string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{msg})
+}
+
+// defaultWatchFileInterval is the interval WatchFile polls at if it is given none.
+const defaultWatchFileInterval = 10 * time.Second
+
+// WatchFile polls `filename` every `interval` (10 seconds if it isn't positive) and sets the
+// level to the level name it contains, e.g. "debug", whenever the file changes. A missing file
+// leaves the level unchanged. Call the returned function to stop watching; it can safely be
+// called more than once.
+func (c *LevelController) WatchFile(filename string, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = defaultWatchFileInterval
+	}
+	done := make(chan struct{})
+	var last string
+	check := func() {
+		fileBytes, err := ioutil.ReadFile(filename)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("WARN: kayvee logger could not read log level file '%s': %s", filename, err)
+			}
+			return
+		}
+		content := strings.TrimSpace(string(fileBytes))
+		if content == last {
+			return
+		}
+		last = content
+		logLvl, err := ParseLogLevel(content)
+		if err != nil {
+			log.Printf("WARN: kayvee logger ignoring log level file '%s': %s", filename, err)
+			return
+		}
+		c.SetLevel(logLvl)
+	}
+
+	check()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				check()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// ParseLogLevel returns the LogLevel named `name`, e.g. "debug". Names are case-insensitive.
+func ParseLogLevel(name string) (LogLevel, error) {
+	for key, val := range logLevelNames {
+		if strings.ToLower(name) == val {
+			return key, nil
+		}
+	}
+	return Trace, fmt.Errorf("unknown log level '%s'", name)
+}
+
+// globalLevelCtl is the controller set with SetGlobalLevelController, or nil.
+var globalLevelCtl atomic.Pointer[LevelController]
+
+// SetGlobalLevelController subscribes every logger created afterwards to `c`, including the
+// loggers the middleware package creates for each request and the one FromContext returns
+// when a context has none, so that one controller sets the level of the whole process:
+//
+//	c := logger.NewLevelController(logger.Info)
+//	logger.SetGlobalLevelController(c)
+//	defer c.NotifySignals()()
+//
+// Those loggers ignore KAYVEE_LOG_LEVEL. SetLogLevel or SetConfig on one of them doesn't change
+// the global level: it gives that logger and its children a controller of their own instead.
+// Change the level with c.SetLevel, or subscribe loggers explicitly with SetLevelController.
+// Passing nil goes back to creating each logger with a controller of its own.
+func SetGlobalLevelController(c *LevelController) {
+	globalLevelCtl.Store(c)
+}
+
+// SetLevelController subscribes the logger and its children to `c`, so that their log level
+// follows the controller's. Loggers subscribed to the same controller share a level, and
+// SetLogLevel on any of them changes it for all.
+func (l *Logger) SetLevelController(c *LevelController) {
+	l.levelCtl.Store(c)
+	l.globalLevel.Store(false)
+}
+
+// LevelController returns the controller holding the logger's level.
+func (l *Logger) LevelController() *LevelController {
+	return l.levelCtl.Load()
+}
+
+// logLevel returns the current minimum level of lines logged.
+func (l *Logger) logLevel() LogLevel {
+	return l.levelCtl.Load().Level()
+}
@@ -0,0 +1,172 @@
+package logger
+
+import (
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/Clever/kayvee-go/v7/router"
+)
+
+// LevelOverrides sets the minimum level logged for particular titles or sources, in place of
+// the logger's level. A title override takes precedence over a source override.
+type LevelOverrides struct {
+	// Titles maps title globs, e.g. "db-*", to a level. An exact title match is preferred over
+	// a glob, and a longer glob over a shorter one. Globs use path.Match syntax, so `*` doesn't
+	// match '/': "api-*" matches "api-get" but not "api-get/users", which needs "api-*/*".
+	Titles map[string]LogLevel
+	// Sources maps sources to a level.
+	Sources map[string]LogLevel
+}
+
+// levelOverrides is the compiled form of LevelOverrides.
+type levelOverrides struct {
+	titles  map[string]LogLevel
+	globs   []titleGlob
+	sources map[string]LogLevel
+	// lowest is the lowest level of any override.
+	lowest LogLevel
+}
+
+type titleGlob struct {
+	pattern string
+	level   LogLevel
+}
+
+// SetLevelOverrides replaces the level overrides of the logger and its children. Passing empty
+// overrides turns them off.
+func (l *Logger) SetLevelOverrides(overrides LevelOverrides) error {
+	if len(overrides.Titles) == 0 && len(overrides.Sources) == 0 {
+		l.levelOverrides.Store(nil)
+		return nil
+	}
+	o, err := newLevelOverrides(overrides)
+	if err != nil {
+		return err
+	}
+	l.levelOverrides.Store(o)
+	return nil
+}
+
+// SetLevelOverridesFromConfig sets the level overrides of the logger from the `levels` section
+// of the kvconfig file `filename`. See router.LevelOverridesFromConfig.
+func (l *Logger) SetLevelOverridesFromConfig(filename string) error {
+	levels, err := router.LevelOverridesFromConfig(filename)
+	if err != nil {
+		return err
+	}
+	return l.setRouterLevelOverrides(levels)
+}
+
+// SetLevelOverridesFromConfigBytes is like SetLevelOverridesFromConfig, with the kvconfig
+// passed as bytes.
+func (l *Logger) SetLevelOverridesFromConfigBytes(fileBytes []byte) error {
+	levels, err := router.LevelOverridesFromConfigBytes(fileBytes)
+	if err != nil {
+		return err
+	}
+	return l.setRouterLevelOverrides(levels)
+}
+
+func (l *Logger) setRouterLevelOverrides(levels router.LevelOverrides) error {
+	overrides := LevelOverrides{
+		Titles:  map[string]LogLevel{},
+		Sources: map[string]LogLevel{},
+	}
+	for _, section := range []struct {
+		names map[string]string
+		out   map[string]LogLevel
+	}{{levels.Titles, overrides.Titles}, {levels.Sources, overrides.Sources}} {
+		for name, levelName := range section.names {
+			logLvl, err := ParseLogLevel(levelName)
+			if err != nil {
+				return err
+			}
+			section.out[name] = logLvl
+		}
+	}
+	return l.SetLevelOverrides(overrides)
+}
+
+func newLevelOverrides(overrides LevelOverrides) (*levelOverrides, error) {
+	o := &levelOverrides{
+		titles:  map[string]LogLevel{},
+		sources: map[string]LogLevel{},
+		lowest:  Critical,
+	}
+	for pattern, logLvl := range overrides.Titles {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid title glob '%s' in level overrides: %s", pattern, err)
+		}
+		o.titles[pattern] = logLvl
+		o.globs = append(o.globs, titleGlob{pattern: pattern, level: logLvl})
+		if logLvl < o.lowest {
+			o.lowest = logLvl
+		}
+	}
+	sort.Slice(o.globs, func(i, j int) bool {
+		if len(o.globs[i].pattern) != len(o.globs[j].pattern) {
+			return len(o.globs[i].pattern) > len(o.globs[j].pattern)
+		}
+		return o.globs[i].pattern < o.globs[j].pattern
+	})
+	for source, logLvl := range overrides.Sources {
+		o.sources[source] = logLvl
+		if logLvl < o.lowest {
+			o.lowest = logLvl
+		}
+	}
+	return o, nil
+}
+
+// forTitle returns the override for `title`, if any.
+func (o *levelOverrides) forTitle(title string) (LogLevel, bool) {
+	if logLvl, ok := o.titles[title]; ok {
+		return logLvl, true
+	}
+	for _, glob := range o.globs {
+		if ok, _ := path.Match(glob.pattern, title); ok {
+			return glob.level, true
+		}
+	}
+	return Trace, false
+}
+
+// minLevel returns the minimum level logged for a line with `title` and the fields in `data`,
+// taking level overrides into account.
+func (l *Logger) minLevel(title string, data map[string]interface{}) LogLevel {
+	source, ok := data["source"].(string)
+	return l.minLevelFor(title, source, ok)
+}
+
+// minLevelFor is like minLevel, for a line whose `source` field is `source` if hasSource is
+// true, and the logger's source otherwise.
+func (l *Logger) minLevelFor(title, source string, hasSource bool) LogLevel {
+	o := l.levelOverrides.Load()
+	if o == nil {
+		return l.logLevel()
+	}
+	if logLvl, ok := o.forTitle(title); ok {
+		return logLvl
+	}
+	if len(o.sources) > 0 {
+		if !hasSource {
+			l.globalsL.RLock()
+			source, _ = l.globals["source"].(string)
+			l.globalsL.RUnlock()
+		}
+		if logLvl, ok := o.sources[source]; ok {
+			return logLvl
+		}
+	}
+	return l.logLevel()
+}
+
+// lowestLevel returns the lowest level that could be logged for any title or source.
+func (l *Logger) lowestLevel() LogLevel {
+	logLvl := l.logLevel()
+	if o := l.levelOverrides.Load(); o != nil && o.lowest < logLvl {
+		return o.lowest
+	}
+	return logLvl
+}
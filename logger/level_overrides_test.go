@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loggedTitles(t *testing.T, buf *bytes.Buffer) []string {
+	titles := []string{}
+	for _, line := range decodeLines(t, buf) {
+		titles = append(titles, line["title"].(string))
+	}
+	return titles
+}
+
+func TestLevelOverridesByTitle(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	lg.SetLogLevel(Info)
+	require.NoError(t, lg.SetLevelOverrides(LevelOverrides{
+		Titles: map[string]LogLevel{
+			"db-*":         Warning,
+			"db-slow-*":    Debug,
+			"db-slow-scan": Error,
+		},
+	}))
+
+	lg.Info("db-query")
+	lg.Warn("db-insert")
+	lg.Debug("db-slow-query")
+	lg.Warn("db-slow-scan")
+	lg.Debug("other")
+	lg.Info("other")
+	lg.DebugCtx(nil, "db-slow-ctx", M{})
+	lg.Info("db-query/users") // `*` doesn't match '/', so this has the logger's level
+	assert.Equal(t, []string{"db-insert", "db-slow-query", "other", "db-slow-ctx", "db-query/users"}, loggedTitles(t, buf))
+
+	t.Log("empty overrides turn them off")
+	buf.Reset()
+	require.NoError(t, lg.SetLevelOverrides(LevelOverrides{}))
+	lg.Debug("db-slow-query")
+	lg.Info("db-query")
+	assert.Equal(t, []string{"db-query"}, loggedTitles(t, buf))
+
+	assert.Error(t, lg.SetLevelOverrides(LevelOverrides{Titles: map[string]LogLevel{"db-[": Info}}))
+}
+
+func TestLevelOverridesBySource(t *testing.T) {
+	buf := &bytes.Buffer{}
+	noisy := NewConcreteLogger("noisy")
+	quiet := NewConcreteLogger("quiet")
+	overrides := LevelOverrides{
+		Titles:  map[string]LogLevel{"important": Trace},
+		Sources: map[string]LogLevel{"noisy": Error},
+	}
+	for _, lg := range []*Logger{noisy, quiet} {
+		lg.SetOutput(buf)
+		require.NoError(t, lg.SetLevelOverrides(overrides))
+	}
+	child := noisy.With(M{"a": "b"})
+
+	noisy.Warn("noisy-warn")
+	child.Warn("noisy-warn")
+	noisy.Error("noisy-error")
+	noisy.Debug("important")
+	quiet.Warn("quiet-warn")
+	assert.Equal(t, []string{"noisy-error", "important", "quiet-warn"}, loggedTitles(t, buf))
+}
+
+func TestLevelOverridesSlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	lg.SetLogLevel(Warning)
+	require.NoError(t, lg.SetLevelOverrides(LevelOverrides{Titles: map[string]LogLevel{"debug-me": Debug}}))
+
+	sl := NewSlogLogger(lg)
+	assert.True(t, sl.Enabled(nil, slog.LevelDebug))
+	sl.Debug("debug-me")
+	sl.Debug("other")
+	assert.Equal(t, []string{"debug-me"}, loggedTitles(t, buf))
+}
+
+func TestSetLevelOverridesFromConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kayvee-levels")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "kvconfig.yml")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`
+routes: {}
+levels:
+  titles:
+    db-*: warning
+  sources:
+    logger-tester: error
+`), 0644))
+
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	require.NoError(t, lg.SetLevelOverridesFromConfig(filename))
+	lg.Info("db-query")
+	lg.Warn("db-insert")
+	lg.Warn("other")
+	lg.Error("other-error")
+	assert.Equal(t, []string{"db-insert", "other-error"}, loggedTitles(t, buf))
+
+	assert.Error(t, lg.SetLevelOverridesFromConfigBytes([]byte("routes: {}\nlevels:\n  titles:\n    db-*: loud\n")))
+	assert.Error(t, lg.SetLevelOverridesFromConfig(filepath.Join(dir, "missing.yml")))
+
+	t.Log("a config without a levels section turns overrides off")
+	require.NoError(t, lg.SetLevelOverridesFromConfigBytes([]byte("routes: {}\n")))
+	buf.Reset()
+	lg.Info("db-query")
+	assert.Equal(t, []string{"db-query"}, loggedTitles(t, buf))
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// NotifySignals makes the controller step its level on signals: SIGUSR1 makes logging more
+// verbose by one level (e.g. info to debug), and SIGUSR2 makes it less verbose. Call the
+// returned function to stop handling the signals; it can safely be called more than once.
+func (c *LevelController) NotifySignals() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				if sig == syscall.SIGUSR1 {
+					c.stepLevel(-1)
+				} else {
+					c.stepLevel(1)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package logger
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelControllerSignals(t *testing.T) {
+	c := NewLevelController(Info)
+	stop := c.NotifySignals()
+	defer stop()
+
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	assert.Eventually(t, func() bool { return c.Level() == Debug }, time.Second, time.Millisecond)
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+	assert.Eventually(t, func() bool { return c.Level() == Info }, time.Second, time.Millisecond)
+	stop()
+}
@@ -0,0 +1,6 @@
+package logger
+
+// NotifySignals is a no-op on Windows, which has no SIGUSR1 or SIGUSR2.
+func (c *LevelController) NotifySignals() (stop func()) {
+	return func() {}
+}
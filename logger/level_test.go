@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelControllerSharedByLoggers(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c := NewLevelController(Info)
+	lg1 := NewConcreteLogger("logger-tester")
+	lg2 := NewConcreteLogger("logger-tester")
+	for _, lg := range []*Logger{lg1, lg2} {
+		lg.SetOutput(buf)
+		lg.SetLevelController(c)
+	}
+	child := lg1.With(M{"a": "b"})
+
+	lg1.Debug("hidden")
+	child.Debug("hidden")
+	c.SetLevel(Debug)
+	lg2.Debug("shown")
+	child.Debug("shown")
+	lg2.SetLogLevel(Error)
+	lg1.Warn("hidden")
+	assert.Equal(t, Error, c.Level())
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Equal(t, "shown", line["title"])
+	}
+}
+
+func TestGlobalLevelController(t *testing.T) {
+	t.Setenv("KAYVEE_LOG_LEVEL", "critical")
+	c := NewLevelController(Info)
+	SetGlobalLevelController(c)
+	defer SetGlobalLevelController(nil)
+
+	buf := &bytes.Buffer{}
+	lg := New("logger-tester")
+	lg.SetOutput(buf)
+	assert.Equal(t, Info, c.Level(), "new loggers don't set the global level")
+	fromCtx := FromContext(context.Background())
+	fromCtx.SetOutput(buf)
+
+	lg.Debug("hidden")
+	c.SetLevel(Debug)
+	lg.Debug("shown")
+	fromCtx.Debug("shown")
+	assert.Len(t, decodeLines(t, buf), 2)
+
+	t.Log("setting the level of one logger detaches it from the global controller")
+	detached := NewConcreteLogger("logger-tester")
+	child := detached.With(M{"a": "b"}).(*Logger)
+	detached.SetConfig("logger-tester", Warning, kv.Format, buf)
+	assert.Equal(t, Debug, c.Level())
+	assert.Equal(t, Warning, child.LevelController().Level())
+	assert.NotSame(t, c, detached.LevelController())
+	detached.SetLogLevel(Error)
+	assert.Equal(t, Error, child.LevelController().Level(), "the logger keeps its own controller")
+	assert.Same(t, c, lg.(*Logger).LevelController())
+
+	explicit := NewConcreteLogger("logger-tester")
+	explicit.SetLevelController(c)
+	explicit.SetLogLevel(Info)
+	assert.Equal(t, Info, c.Level(), "explicit subscribers share the level")
+
+	SetGlobalLevelController(nil)
+	assert.NotSame(t, c, NewConcreteLogger("logger-tester").LevelController())
+}
+
+func TestLevelControllerConcurrentSet(t *testing.T) {
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(ioutil.Discard)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				lg.SetLogLevel(LogLevel(j % 6))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				lg.Info("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLevelControllerHTTP(t *testing.T) {
+	c := NewLevelController(Info)
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"level":"info"}`, string(body))
+
+	put := func(body string) (int, string) {
+		req, err := http.NewRequest(http.MethodPut, srv.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(respBody)
+	}
+	status, body2 := put(`{"level":"DEBUG"}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.JSONEq(t, `{"level":"debug"}`, body2)
+	assert.Equal(t, Debug, c.Level())
+
+	status, _ = put(`{"level":"loud"}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = put(`not json`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, Debug, c.Level())
+
+	resp, err = http.Post(srv.URL, "application/json", strings.NewReader(`{"level":"info"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, Debug, c.Level())
+}
+
+func TestLevelControllerStepBounds(t *testing.T) {
+	c := NewLevelController(Trace)
+	assert.Equal(t, Trace, c.stepLevel(-1))
+	c.SetLevel(Critical)
+	assert.Equal(t, Critical, c.stepLevel(1))
+	assert.Equal(t, Error, c.stepLevel(-1))
+}
+
+func TestLevelControllerWatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kayvee-level")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "level")
+
+	c := NewLevelController(Info)
+	stop := c.WatchFile(filename, time.Millisecond)
+	defer stop()
+	assert.Equal(t, Info, c.Level(), "missing file should leave the level unchanged")
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte("warning\n"), 0644))
+	assert.Eventually(t, func() bool { return c.Level() == Warning }, time.Second, time.Millisecond)
+
+	c.SetLevel(Debug)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, Debug, c.Level(), "unchanged file should not reset the level")
+
+	require.NoError(t, ioutil.WriteFile(filename, []byte("critical"), 0644))
+	assert.Eventually(t, func() bool { return c.Level() == Critical }, time.Second, time.Millisecond)
+	stop()
+}
+
+func TestLevelControllerWatchFileDefaultInterval(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "level")
+	require.NoError(t, ioutil.WriteFile(filename, []byte("error"), 0644))
+	c := NewLevelController(Info)
+	stop := c.WatchFile(filename, 0)
+	assert.Equal(t, Error, c.Level(), "the file is read before the first interval")
+	stop()
+	stop()
+}
+
+func TestParseLogLevel(t *testing.T) {
+	lvl, err := ParseLogLevel("Warning")
+	require.NoError(t, err)
+	assert.Equal(t, Warning, lvl)
+	_, err = ParseLogLevel("verbose")
+	assert.Error(t, err)
+}
@@ -1,14 +1,17 @@
 package logger
 
 import (
+	"context"
 	"io"
 	"log"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/Clever/kayvee-go/v7/redact"
 	"github.com/Clever/kayvee-go/v7/router"
 	wcl "github.com/Clever/wag/logging/wagclientlogger"
 )
@@ -65,25 +68,114 @@ func (l LogLevel) String() string {
 	return ""
 }
 
-// metricsOutput is an enum is used to denote the output of metrics
-type metricsOutput int
+// MetricsOutput is an enum is used to denote the output of metrics
+type MetricsOutput int
 
 // Constants used to define different MetricsOutput supported
 const (
-	logMetrics metricsOutput = iota
+	// LogMetrics writes metrics as kayvee log lines
+	LogMetrics MetricsOutput = iota
+	// OTLMetrics records metrics to OpenTelemetry instruments instead of logging them
+	OTLMetrics
+	// LogAndOTLMetrics writes metrics as kayvee log lines and records them to OpenTelemetry instruments
+	LogAndOTLMetrics
 )
 
+var metricsOutputNames = map[MetricsOutput]string{
+	LogMetrics:       "log",
+	OTLMetrics:       "otl",
+	LogAndOTLMetrics: "both",
+}
+
+func (m MetricsOutput) String() string {
+	return metricsOutputNames[m]
+}
+
 // Timer is a helper structure used in logger.Timer method
 type Timer struct {
 	Logger    *Logger
 	StartedAt time.Time
 	Title     string
+
+	// data is a copy of the data passed to TimerD, logged with every line of the timer.
+	data    M
+	mu      sync.Mutex
+	laps    M
+	lastLap time.Time
+}
+
+// Lap logs a `<title>-lap` line at Debug for the checkpoint `name`, with the time elapsed since
+// the timer started and since the previous lap. The elapsed time of each lap is also included
+// in the line logged when the timer is stopped. Returns the time elapsed since the previous lap.
+func (t *Timer) Lap(name string) time.Duration {
+	now := t.Logger.now()
+	t.mu.Lock()
+	last := t.lastLap
+	if last.IsZero() {
+		last = t.StartedAt
+	}
+	t.lastLap = now
+	elapsed := now.Sub(t.StartedAt)
+	if t.laps == nil {
+		t.laps = M{}
+	}
+	t.laps[name] = elapsed.Seconds()
+	t.mu.Unlock()
+
+	lapTime := now.Sub(last)
+	data := t.copyData()
+	data["lap"] = name
+	data["elapsedTimeSeconds"] = elapsed.Seconds()
+	data["lapTimeSeconds"] = lapTime.Seconds()
+	t.Logger.DebugD(t.Title+"-lap", data)
+	return lapTime
 }
 
 // Stop is a helper function used in logger.Timer method
-func (t *Timer) Stop() {
-	elapsedTimeSeconds := time.Now().Sub(t.StartedAt).Seconds()
-	t.Logger.DebugD(t.Title+"-end", M{"elapsedTimeSeconds": elapsedTimeSeconds})
+// It logs `<title>-end` at Debug and returns the time elapsed since the timer started.
+func (t *Timer) Stop() time.Duration {
+	return t.StopWithLevel(Debug)
+}
+
+// StopWithLevel is like Stop, but logs `<title>-end` at the given level.
+func (t *Timer) StopWithLevel(logLvl LogLevel) time.Duration {
+	elapsed, data := t.stop()
+	data["elapsedTimeSeconds"] = elapsed.Seconds()
+	data["title"] = t.Title + "-end"
+	t.Logger.logWithLevel(logLvl, data)
+	return elapsed
+}
+
+// StopAsGauge stops the timer and logs `<title>-end` as a gauge (type = gauge) whose value is
+// the elapsed time in milliseconds, so it can be routed to metrics. Returns the elapsed time.
+func (t *Timer) StopAsGauge() time.Duration {
+	elapsed, data := t.stop()
+	t.Logger.GaugeFloatD(t.Title+"-end", float64(elapsed)/float64(time.Millisecond), data)
+	return elapsed
+}
+
+// stop returns the time elapsed since the timer started, and the data to log it with.
+func (t *Timer) stop() (time.Duration, M) {
+	elapsed := t.Logger.now().Sub(t.StartedAt)
+	data := t.copyData()
+	t.mu.Lock()
+	if len(t.laps) > 0 {
+		laps := map[string]interface{}{}
+		for k, v := range t.laps {
+			laps[k] = v
+		}
+		data["laps"] = laps
+	}
+	t.mu.Unlock()
+	return elapsed, data
+}
+
+func (t *Timer) copyData() M {
+	data := make(M, len(t.data)+4)
+	for k, v := range t.data {
+		data[k] = v
+	}
+	return data
 }
 
 /////////////////////////////
@@ -94,16 +186,78 @@ func (t *Timer) Stop() {
 
 // Logger is the default implementation of KayveeLogger.
 // It provides customization of globals, default log level, formatting, and output destination.
+// Its setters are safe to call while other goroutines are logging.
 type Logger struct {
-	globalsL      sync.RWMutex
-	globals       map[string]interface{}
-	logLvl        LogLevel
+	globalsL sync.RWMutex
+	globals  map[string]interface{}
+	// callerSkip is the number of frames skipped when finding the caller, see WithCallerSkip.
+	callerSkip int
+	*loggerConfig
+}
+
+// loggerConfig holds the configuration that a Logger shares with the child loggers derived
+// from it via With.
+type loggerConfig struct {
+	levelCtl atomic.Pointer[LevelController]
+	// globalLevel is whether levelCtl is the global controller, which SetLogLevel detaches
+	// from rather than changing.
+	globalLevel      atomic.Bool
+	levelOverrides   atomic.Pointer[levelOverrides]
+	errorStacks      atomic.Bool
+	callerAnnotation atomic.Int32
+	timestampFormat  atomic.Int32
+	redaction        atomic.Pointer[redact.Policy]
+	// settings is read once per line, so it is swapped rather than modified; settingsL
+	// serializes the swaps.
+	settingsL sync.Mutex
+	settings  atomic.Pointer[settings]
+}
+
+// settings is the part of a loggerConfig that isn't a single value. A published settings is
+// never modified, so a line is logged with one consistent snapshot of it, and reconfiguring a
+// logger while it is in use needs no lock on the logging path.
+type settings struct {
 	fLogger       formatLogger
 	logRouter     router.Router
-	metricsOutput metricsOutput
+	metricsOutput MetricsOutput
+	meter         *otlMeter
+	// metricGlobals are the globals that become metric attributes; nil means the default ones.
+	metricGlobals []string
+	sampler       *sampler
+	clock         func() time.Time
+	// ttyFormatter is whether the formatter is the pretty one picked because stderr is a
+	// terminal, which SetOutput replaces with kv.Format when the output is anything else.
+	ttyFormatter bool
+}
+
+// updateSettings publishes a copy of the settings changed by `update`.
+func (c *loggerConfig) updateSettings(update func(s *settings)) {
+	c.settingsL.Lock()
+	defer c.settingsL.Unlock()
+	s := *c.settings.Load()
+	update(&s)
+	c.settings.Store(&s)
+}
+
+// globalRouter is the router set with SetGlobalRouting, used by loggers without a router of
+// their own.
+var globalRouter atomic.Pointer[router.Router]
+
+// loadGlobalRouter returns the router set with SetGlobalRouting, or nil.
+func loadGlobalRouter() router.Router {
+	if r := globalRouter.Load(); r != nil {
+		return *r
+	}
+	return nil
 }
 
-var globalRouter router.Router
+func storeGlobalRouter(r router.Router) {
+	if r == nil {
+		globalRouter.Store(nil)
+		return
+	}
+	globalRouter.Store(&r)
+}
 
 var reservedKeyNames = map[string]bool{
 	"title":   true,
@@ -118,8 +272,8 @@ var reservedKeyNames = map[string]bool{
 // configuration specified in `filename`. For convenience, the KayveeLogger is expected
 // to return itself as the first return value.
 func SetGlobalRouting(filename string) error {
-	var err error
-	globalRouter, err = router.NewFromConfig(filename)
+	r, err := router.NewFromConfig(filename)
+	storeGlobalRouter(r)
 	return err
 }
 
@@ -127,23 +281,25 @@ func SetGlobalRouting(filename string) error {
 // configuration specified in . For convenience, the KayveeLogger is expected
 // to return itself as the first return value.
 func SetGlobalRoutingFromBytes(fileBytes []byte) error {
-	var err error
-	globalRouter, err = router.NewFromConfigBytes(fileBytes)
+	r, err := router.NewFromConfigBytes(fileBytes)
+	storeGlobalRouter(r)
 	return err
 }
 
 // SetConfig implements the method for the KayveeLogger interface.
 func (l *Logger) SetConfig(source string, logLvl LogLevel, formatter Formatter, output io.Writer) {
 	l.globalsL.Lock()
-	defer l.globalsL.Unlock()
-
 	if l.globals == nil {
 		l.globals = make(map[string]interface{})
 	}
 	l.globals["source"] = source
-	l.logLvl = logLvl
-	l.fLogger.setFormatter(formatter)
-	l.fLogger.setOutput(output)
+	l.globalsL.Unlock()
+
+	l.SetLogLevel(logLvl)
+	l.updateSettings(func(s *settings) {
+		s.fLogger = s.fLogger.withFormatter(formatter).withOutput(output)
+		s.ttyFormatter = false
+	})
 }
 
 // AddContext implements the method for the KayveeLogger interface.
@@ -153,6 +309,28 @@ func (l *Logger) AddContext(key, val string) {
 	updateContextMapIfNotReserved(l.globals, key, val)
 }
 
+// With implements the method for the KayveeLogger interface.
+func (l *Logger) With(fields M) KayveeLogger {
+	return l.with(fields)
+}
+
+func (l *Logger) with(fields M) *Logger {
+	l.globalsL.RLock()
+	defer l.globalsL.RUnlock()
+	ctx := make(map[string]interface{}, len(l.globals)+len(fields))
+	for k, v := range l.globals {
+		ctx[k] = v
+	}
+	for k, v := range fields {
+		updateContextMapIfNotReserved(ctx, k, v)
+	}
+	return &Logger{
+		globals:      ctx,
+		callerSkip:   l.callerSkip,
+		loggerConfig: l.loggerConfig,
+	}
+}
+
 // GetContext implements the method for the KayveeLogger interface.
 func (l *Logger) GetContext(key string) (interface{}, bool) {
 	l.globalsL.RLock()
@@ -163,56 +341,101 @@ func (l *Logger) GetContext(key string) (interface{}, bool) {
 
 // SetRouter implements the method for the KayveeLogger interface.
 func (l *Logger) SetRouter(router router.Router) {
-	l.logRouter = router
+	l.updateSettings(func(s *settings) {
+		s.logRouter = router
+	})
 }
 
 // SetLogLevel implements the method for the KayveeLogger interface.
+// The level is set on the logger's LevelController, so it applies to every logger subscribed
+// to it, unless the logger was subscribed to the global controller when it was created (see
+// SetGlobalLevelController): it then gets a controller of its own instead.
 func (l *Logger) SetLogLevel(logLvl LogLevel) {
-	l.logLvl = logLvl
+	if l.globalLevel.CompareAndSwap(true, false) {
+		l.levelCtl.Store(NewLevelController(logLvl))
+		return
+	}
+	l.LevelController().SetLevel(logLvl)
+}
+
+// SetMetricsOutput sets where Counter, Gauge and Histogram metrics are sent. It is shared with child
+// loggers created via With.
+func (l *Logger) SetMetricsOutput(output MetricsOutput) {
+	l.updateSettings(func(s *settings) {
+		s.metricsOutput = output
+	})
+}
+
+// SetClock sets the function the logger and its children use to read the current time, e.g.
+// for Timers. Mostly used for testing.
+func (l *Logger) SetClock(clock func() time.Time) {
+	l.updateSettings(func(s *settings) {
+		s.clock = clock
+	})
+}
+
+func (l *Logger) now() time.Time {
+	if clock := l.settings.Load().clock; clock != nil {
+		return clock()
+	}
+	return time.Now()
 }
 
 // SetFormatter implements the method for the KayveeLogger interface.
 func (l *Logger) SetFormatter(formatter Formatter) {
-	l.fLogger.setFormatter(formatter)
+	l.updateSettings(func(s *settings) {
+		s.fLogger = s.fLogger.withFormatter(formatter)
+		s.ttyFormatter = false
+	})
 }
 
-// SetOutput implements the method for the KayveeLogger interface.
+// SetOutput implements the method for the KayveeLogger interface. If the pretty formatter was
+// picked because stderr is a terminal, any output other than stderr goes back to kv.Format, so
+// that files and collectors get JSON lines.
 func (l *Logger) SetOutput(output io.Writer) {
-	l.fLogger.setOutput(output)
+	l.updateSettings(func(s *settings) {
+		s.fLogger = s.fLogger.withOutput(output)
+		if s.ttyFormatter && output != io.Writer(os.Stderr) {
+			s.fLogger = s.fLogger.withFormatter(kv.Format)
+			s.ttyFormatter = false
+		}
+	})
 }
 
 func (l *Logger) setFormatLogger(fl formatLogger) {
-	l.fLogger = fl
+	l.updateSettings(func(s *settings) {
+		s.fLogger = fl
+	})
 }
 
 // Trace implements the method for the KayveeLogger interface.
-func (l *Logger) Trace(title string) {
-	l.TraceD(title, M{})
+func (l *Logger) Trace(title string, fields ...Field) {
+	l.logFields(Trace, title, fields)
 }
 
 // Debug implements the method for the KayveeLogger interface.
-func (l *Logger) Debug(title string) {
-	l.DebugD(title, M{})
+func (l *Logger) Debug(title string, fields ...Field) {
+	l.logFields(Debug, title, fields)
 }
 
 // Info implements the method for the KayveeLogger interface.
-func (l *Logger) Info(title string) {
-	l.InfoD(title, M{})
+func (l *Logger) Info(title string, fields ...Field) {
+	l.logFields(Info, title, fields)
 }
 
 // Warn implements the method for the KayveeLogger interface.
-func (l *Logger) Warn(title string) {
-	l.WarnD(title, M{})
+func (l *Logger) Warn(title string, fields ...Field) {
+	l.logFields(Warning, title, fields)
 }
 
 // Error implements the method for the KayveeLogger interface.
-func (l *Logger) Error(title string) {
-	l.ErrorD(title, M{})
+func (l *Logger) Error(title string, fields ...Field) {
+	l.logFields(Error, title, fields)
 }
 
 // Critical implements the method for the KayveeLogger interface.
-func (l *Logger) Critical(title string) {
-	l.CriticalD(title, M{})
+func (l *Logger) Critical(title string, fields ...Field) {
+	l.logFields(Critical, title, fields)
 }
 
 // Counter implements the method for the KayveeLogger interface.
@@ -233,49 +456,76 @@ func (l *Logger) GaugeFloat(title string, value float64) {
 	l.GaugeFloatD(title, value, M{})
 }
 
+// Histogram implements the method for the KayveeLogger interface.
+// Logs with type = histogram, and value = value
+func (l *Logger) Histogram(title string, value float64) {
+	l.HistogramD(title, value, M{})
+}
+
 // TraceD implements the method for the KayveeLogger interface.
 func (l *Logger) TraceD(title string, data map[string]interface{}) {
-	data["title"] = title
-	l.logWithLevel(Trace, data)
+	l.logData(Trace, title, data)
 }
 
 // DebugD implements the method for the KayveeLogger interface.
 func (l *Logger) DebugD(title string, data map[string]interface{}) {
-	data["title"] = title
-	l.logWithLevel(Debug, data)
+	l.logData(Debug, title, data)
 }
 
 // InfoD implements the method for the KayveeLogger interface.
 func (l *Logger) InfoD(title string, data map[string]interface{}) {
-	data["title"] = title
-	l.logWithLevel(Info, data)
+	l.logData(Info, title, data)
 }
 
 // WarnD implements the method for the KayveeLogger interface.
 func (l *Logger) WarnD(title string, data map[string]interface{}) {
-	data["title"] = title
-	l.logWithLevel(Warning, data)
+	l.logData(Warning, title, data)
 }
 
 // ErrorD implements the method for the KayveeLogger interface.
 func (l *Logger) ErrorD(title string, data map[string]interface{}) {
-	data["title"] = title
-	l.logWithLevel(Error, data)
+	l.logData(Error, title, data)
 }
 
 // CriticalD implements the method for the KayveeLogger interface.
 func (l *Logger) CriticalD(title string, data map[string]interface{}) {
-	data["title"] = title
-	l.logWithLevel(Critical, data)
+	l.logData(Critical, title, data)
+}
+
+// TraceCtx implements the method for the KayveeLogger interface.
+func (l *Logger) TraceCtx(ctx context.Context, title string, data map[string]interface{}) {
+	l.logWithContext(ctx, Trace, title, data)
+}
+
+// DebugCtx implements the method for the KayveeLogger interface.
+func (l *Logger) DebugCtx(ctx context.Context, title string, data map[string]interface{}) {
+	l.logWithContext(ctx, Debug, title, data)
+}
+
+// InfoCtx implements the method for the KayveeLogger interface.
+func (l *Logger) InfoCtx(ctx context.Context, title string, data map[string]interface{}) {
+	l.logWithContext(ctx, Info, title, data)
+}
+
+// WarnCtx implements the method for the KayveeLogger interface.
+func (l *Logger) WarnCtx(ctx context.Context, title string, data map[string]interface{}) {
+	l.logWithContext(ctx, Warning, title, data)
+}
+
+// ErrorCtx implements the method for the KayveeLogger interface.
+func (l *Logger) ErrorCtx(ctx context.Context, title string, data map[string]interface{}) {
+	l.logWithContext(ctx, Error, title, data)
+}
+
+// CriticalCtx implements the method for the KayveeLogger interface.
+func (l *Logger) CriticalCtx(ctx context.Context, title string, data map[string]interface{}) {
+	l.logWithContext(ctx, Critical, title, data)
 }
 
 // CounterD implements the method for the KayveeLogger interface.
 // Logs with type = up/down counter, and value = value
 func (l *Logger) CounterD(title string, value int, data map[string]interface{}) {
-	data["title"] = title
-	data["value"] = value
-	data["type"] = "counter"
-	l.logWithLevel(Info, data)
+	l.logMetric(title, value, "counter", data)
 }
 
 // GaugeIntD implements the method for the KayveeLogger interface.
@@ -290,6 +540,12 @@ func (l *Logger) GaugeFloatD(title string, value float64, data map[string]interf
 	l.gauge(title, value, data)
 }
 
+// HistogramD implements the method for the KayveeLogger interface.
+// Logs with type = histogram, and value = value
+func (l *Logger) HistogramD(title string, value float64, data map[string]interface{}) {
+	l.logMetric(title, value, "histogram", data)
+}
+
 // Timer implements the method for the KayveeLogger interface.
 // Returns Timer structure with .Stop method
 func (l *Logger) Timer(title string) *Timer {
@@ -299,24 +555,90 @@ func (l *Logger) Timer(title string) *Timer {
 // TimerD implements the method for the KayveeLogger interface.
 // Returns Timer structure with .Stop method
 func (l *Logger) TimerD(title string, data map[string]interface{}) *Timer {
+	t := &Timer{Logger: l, StartedAt: l.now(), Title: title}
+	t.data = t.copyData()
+	for k, v := range data {
+		t.data[k] = v
+	}
 	l.DebugD(title+"-start", data)
-	return &Timer{Logger: l, StartedAt: time.Now(), Title: title}
+	return t
 }
 
 func (l *Logger) gauge(title string, value interface{}, data map[string]interface{}) {
-	data["title"] = title
-	data["value"] = value
-	data["type"] = "gauge"
-	l.logWithLevel(Info, data)
+	l.logMetric(title, value, "gauge", data)
+}
+
+// logMetric sends a counter, gauge or histogram to the outputs selected by the logger's MetricsOutput.
+func (l *Logger) logMetric(title string, value interface{}, metricType string, data map[string]interface{}) {
+	record := newRecord(data)
+	record["title"] = title
+	record["value"] = value
+	record["type"] = metricType
+	switch l.settings.Load().metricsOutput {
+	case OTLMetrics:
+		l.recordOTLMetric(record)
+	case LogAndOTLMetrics:
+		// record first, since logWithLevel adds globals and routing metadata to the record
+		l.recordOTLMetric(record)
+		l.logWithLevel(Info, record)
+	default:
+		l.logWithLevel(Info, record)
+	}
 }
 
-// Actual logging. Handles whether to output based on log level and
-// unifies the passed in data with the stored globals
+// logData logs a copy of data with `title`. The copy is skipped entirely if the line would be
+// filtered out by the log level.
+func (l *Logger) logData(logLvl LogLevel, title string, data map[string]interface{}) {
+	if logLvl < l.minLevel(title, data) {
+		return
+	}
+	record := newRecord(data)
+	record["title"] = title
+	l.logChecked(logLvl, record)
+}
+
+// logWithContext adds the fields extracted from ctx to a copy of data and logs it. Extraction
+// is skipped entirely if the line would be filtered out by the log level.
+func (l *Logger) logWithContext(ctx context.Context, logLvl LogLevel, title string, data map[string]interface{}) {
+	if logLvl < l.minLevel(title, data) {
+		return
+	}
+	record := newRecord(data)
+	addContextFields(ctx, record)
+	record["title"] = title
+	l.logChecked(logLvl, record)
+}
+
+// recordExtraFields is the room newRecord leaves for the fields the pipeline adds, like the
+// level and globals.
+const recordExtraFields = 8
+
+// newRecord returns a copy of data for the logging pipeline to add fields to. Maps passed by
+// callers are never modified, since they may be reused or shared between goroutines.
+func newRecord(data map[string]interface{}) M {
+	record := make(M, len(data)+recordExtraFields)
+	for k, v := range data {
+		record[k] = v
+	}
+	return record
+}
+
+// logWithLevel logs data, unless logLvl is below the minimum level for its title and source.
+// data must be a record owned by the pipeline, e.g. from newRecord, since fields are added to
+// it.
 func (l *Logger) logWithLevel(logLvl LogLevel, data map[string]interface{}) {
-	if logLvl < l.logLvl {
+	title, _ := data["title"].(string)
+	if logLvl < l.minLevel(title, data) {
 		// No log output
 		return
 	}
+	l.logChecked(logLvl, data)
+}
+
+// Actual logging. Unifies the passed in data with the stored globals. Callers must already
+// have checked logLvl against minLevel, which is only evaluated once per line; data is as for
+// logWithLevel.
+func (l *Logger) logChecked(logLvl LogLevel, data map[string]interface{}) {
 	data["level"] = logLvl.String()
 	l.globalsL.RLock()
 	defer l.globalsL.RUnlock()
@@ -327,13 +649,27 @@ func (l *Logger) logWithLevel(logLvl LogLevel, data map[string]interface{}) {
 		}
 		data[key] = value
 	}
-	if l.logRouter != nil {
-		data["_kvmeta"] = l.logRouter.Route(data)
-	} else if globalRouter != nil {
-		data["_kvmeta"] = globalRouter.Route(data)
+	s := l.settings.Load()
+	// redact before routing, so that secrets can't be copied into route outputs
+	data = l.redactionPolicy().Apply(data)
+	if s.sampler != nil {
+		ok, sampleRate := s.sampler.sample(l.now(), logLvl, data)
+		if !ok {
+			return
+		}
+		if sampleRate > 0 {
+			data[sampleRateKey] = sampleRate
+		}
+	}
+	l.addTimestamp(data)
+	l.addCaller(data)
+	if s.logRouter != nil {
+		data["_kvmeta"] = s.logRouter.Route(data)
+	} else if r := loadGlobalRouter(); r != nil {
+		data["_kvmeta"] = r.Route(data)
 	}
 
-	l.fLogger.formatAndLog(data)
+	s.fLogger.formatAndLog(data)
 }
 
 // updateContextMapIfNotReserved updates context[key] to val if key is not in the reserved list.
@@ -370,39 +706,63 @@ type formatLogger interface {
 	// formatAndLog processes the given data map into a log line and writes it
 	formatAndLog(data map[string]interface{})
 
-	// setFormatter specifies the Formatter function to use in formatAndLog
-	setFormatter(formatter Formatter)
+	// withFormatter returns a formatLogger that uses the Formatter function in formatAndLog.
+	// The receiver is not modified, since it may be in use.
+	withFormatter(formatter Formatter) formatLogger
 
-	// setOutput specifies the output destination to use in formatAndLog
-	setOutput(output io.Writer)
+	// withOutput returns a formatLogger that writes to the output destination in formatAndLog.
+	// The receiver is not modified, since it may be in use.
+	withOutput(output io.Writer) formatLogger
 }
 
 // defaultFormatLogger provides default implementation of a formatLogger.
 type defaultFormatLogger struct {
 	formatter Formatter
 	logWriter *log.Logger
+	// kvFormat is whether formatter is kv.Format, so that lines of typed fields can be encoded
+	// without building a map.
+	kvFormat bool
 }
 
 // formatAndLog implements the formatLogger interface for *defaultFormatLogger.
 func (fl *defaultFormatLogger) formatAndLog(data map[string]interface{}) {
-	logString := fl.formatter(data)
+	fl.write(fl.format(data))
+}
+
+// format turns data into a log line using the formatter.
+func (fl *defaultFormatLogger) format(data map[string]interface{}) string {
+	return fl.formatter(data)
+}
+
+// formatDeferred implements the deferredFormatLogger interface for *defaultFormatLogger.
+func (fl *defaultFormatLogger) formatDeferred(data map[string]interface{}) func() {
+	line := fl.format(data)
+	return func() { fl.write(line) }
+}
+
+// write writes a formatted log line to the output.
+func (fl *defaultFormatLogger) write(logString string) {
 	fl.logWriter.Println(logString)
 }
 
-// setFormat implements the formatLogger interface for *defaultFormatLogger.
-func (fl *defaultFormatLogger) setFormatter(formatter Formatter) {
-	fl.formatter = formatter
+// withFormatter implements the formatLogger interface for *defaultFormatLogger.
+func (fl *defaultFormatLogger) withFormatter(formatter Formatter) formatLogger {
+	c := *fl
+	c.formatter = formatter
+	c.kvFormat = isKayveeFormat(formatter)
+	return &c
 }
 
-// setOutput implements the formatLogger interface for *defaultFormatLogger.
-func (fl *defaultFormatLogger) setOutput(output io.Writer) {
-	fl.logWriter = log.New(output, "", 0) // No prefixes
+// withOutput implements the formatLogger interface for *defaultFormatLogger.
+func (fl *defaultFormatLogger) withOutput(output io.Writer) formatLogger {
+	c := *fl
+	c.logWriter = log.New(output, "", 0) // No prefixes
+	return &c
 }
 
 // Log is a basic logging method that fulfills the WagClientLogger interface.
 func (l *Logger) Log(level wcl.LogLevel, title string, m map[string]interface{}) {
-	m["title"] = title
-	l.logWithLevel(LogLevel(level), m)
+	l.logData(LogLevel(level), title, m)
 }
 
 // NewConcreteLogger creates a *logger.Logger. Default values are Debug LogLevel, kayvee Formatter, and std.err output.
@@ -442,28 +802,43 @@ func NewConcreteLoggerWithContext(source string, contextValues M) *Logger {
 		ctx["pod-account"] = os.Getenv("_POD_ACCOUNT")
 	}
 	logObj := Logger{
-		globals: ctx,
+		globals:      ctx,
+		loggerConfig: &loggerConfig{},
 	}
 
-	logObj.metricsOutput = logMetrics
+	metricsOutput := LogMetrics
+	strMetricsOutput := os.Getenv("KAYVEE_METRICS_OUTPUT")
+	for key, val := range metricsOutputNames {
+		if strings.ToLower(strMetricsOutput) == val {
+			metricsOutput = key
+			break
+		}
+	}
+	logObj.settings.Store(&settings{
+		fLogger:       &defaultFormatLogger{},
+		metricsOutput: metricsOutput,
+	})
+
+	logObj.SetTimestampFormat(timestampFormatFromEnv(os.Getenv("KAYVEE_TIMESTAMP_FORMAT")))
 
-	fl := defaultFormatLogger{}
-	logObj.fLogger = &fl
+	logObj.levelCtl.Store(NewLevelController(Trace))
 
 	var logLvl LogLevel
 	strLogLvl := os.Getenv("KAYVEE_LOG_LEVEL")
-	if strLogLvl == "" {
-		logLvl = Trace
-	} else {
-		for key, val := range logLevelNames {
-			if strings.ToLower(strLogLvl) == val {
-				logLvl = key
-				break
-			}
-		}
+	if strLogLvl != "" {
+		logLvl, _ = ParseLogLevel(strLogLvl)
 	}
 
-	logObj.SetConfig(source, logLvl, kv.Format, os.Stderr)
+	formatter, ttyFormatter := formatterFromEnv(os.Getenv("KAYVEE_FORMAT"), kv.Format)
+	logObj.SetConfig(source, logLvl, formatter, os.Stderr)
+	logObj.updateSettings(func(s *settings) {
+		s.ttyFormatter = ttyFormatter
+	})
+	if c := globalLevelCtl.Load(); c != nil {
+		// subscribe after SetConfig, so that the level from the environment isn't set on c
+		logObj.levelCtl.Store(c)
+		logObj.globalLevel.Store(true)
+	}
 
 	return &logObj
 
@@ -2,12 +2,18 @@ package logger
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/Clever/kayvee-go/v7/router"
+	wcl "github.com/Clever/wag/logging/wagclientlogger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -155,6 +161,19 @@ func TestLogGaugeFloat(t *testing.T) {
 		"logger-tester", kv.Info, "testloggauge", map[string]interface{}{"key1": "val1", "key2": "val2", "type": "gauge", "value": 4.0}))
 }
 
+func TestLogHistogram(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New("logger-tester")
+	logger.SetOutput(buf)
+	logger.Histogram("testloghistogram", 0.5)
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Info, "testloghistogram", map[string]interface{}{"type": "histogram", "value": 0.5}))
+	buf.Reset()
+	logger.HistogramD("testloghistogram", 12.5, map[string]interface{}{"key1": "val1", "key2": "val2"})
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Info, "testloghistogram", map[string]interface{}{"key1": "val1", "key2": "val2", "type": "histogram", "value": 12.5}))
+}
+
 func TestDiffOutput(t *testing.T) {
 	buf := &bytes.Buffer{}
 	logger := New("logger-tester")
@@ -291,3 +310,288 @@ func (m *MockRouter) Route(msg map[string]interface{}) map[string]interface{} {
 func TestLoggerImplementsKayveeLogger(t *testing.T) {
 	assert.Implements(t, (*KayveeLogger)(nil), &Logger{}, "*Logger should implement KayveeLogger")
 }
+
+func TestWith(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New("logger-tester")
+	logger.SetOutput(buf)
+	logger.AddContext("a", "b")
+
+	child := logger.With(M{"request": "r1", "title": "ignored"})
+	child.AddContext("c", "d")
+	child.Info("1")
+	assertLogFormatAndCompareContent(t, buf.String(),
+		kv.FormatLog("logger-tester", kv.Info, "1", M{"a": "b", "c": "d", "request": "r1"}))
+
+	t.Log("parent context is not affected by the child")
+	buf.Reset()
+	logger.Info("2")
+	assertLogFormatAndCompareContent(t, buf.String(),
+		kv.FormatLog("logger-tester", kv.Info, "2", M{"a": "b"}))
+
+	t.Log("child shares output and log level with the parent")
+	buf2 := &bytes.Buffer{}
+	logger.SetOutput(buf2)
+	logger.SetLogLevel(Warning)
+	child.Info("3")
+	assert.Equal(t, "", buf2.String())
+	child.Warn("4")
+	assertLogFormatAndCompareContent(t, buf2.String(),
+		kv.FormatLog("logger-tester", kv.Warning, "4", M{"a": "b", "c": "d", "request": "r1"}))
+}
+
+func TestTimer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewConcreteLogger("logger-tester")
+	logger.SetOutput(buf)
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	logger.SetClock(func() time.Time { return now })
+
+	timer := logger.TimerD("testtimer", M{"key1": "val1"})
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.LogLevel(Debug.String()), "testtimer-start", M{"key1": "val1"}))
+
+	buf.Reset()
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, 2*time.Second, timer.Lap("first"))
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.LogLevel(Debug.String()), "testtimer-lap", M{
+			"key1":               "val1",
+			"lap":                "first",
+			"elapsedTimeSeconds": 2,
+			"lapTimeSeconds":     2,
+		}))
+
+	buf.Reset()
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, 2500*time.Millisecond, timer.StopWithLevel(Info))
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Info, "testtimer-end", M{
+			"key1":               "val1",
+			"elapsedTimeSeconds": 2.5,
+			"laps":               M{"first": 2},
+		}))
+
+	buf.Reset()
+	timer = logger.Timer("testtimer")
+	now = now.Add(1500 * time.Microsecond)
+	assert.Equal(t, 1500*time.Microsecond, timer.Stop())
+	assertLogFormatAndCompareContent(t, buf.String()[strings.Index(buf.String(), "\n")+1:], kv.FormatLog(
+		"logger-tester", kv.LogLevel(Debug.String()), "testtimer-end", M{"elapsedTimeSeconds": 0.0015}))
+}
+
+func TestTimerStopAsGauge(t *testing.T) {
+	mockLogger := NewMockCountLogger("logger-tester")
+	testRouter, err := router.NewFromRoutes(map[string]router.Rule{
+		"timer-metric": {
+			Matchers: router.RuleMatchers{"title": []string{"testtimer-end"}, "type": []string{"gauge"}},
+			Output:   router.RuleOutput{"type": "metrics", "series": "timers", "dimensions": []string{"key1"}},
+		},
+	})
+	require.NoError(t, err)
+	mockLogger.SetRouter(testRouter)
+
+	timer := mockLogger.TimerD("testtimer", M{"key1": "val1"})
+	elapsed := timer.StopAsGauge()
+	assert.True(t, elapsed >= 0)
+	assert.Equal(t, map[string]int{"timer-metric": 1}, mockLogger.RuleCounts())
+
+	buf := &bytes.Buffer{}
+	logger := NewConcreteLogger("logger-tester")
+	logger.SetOutput(buf)
+	now := time.Now()
+	logger.SetClock(func() time.Time { return now })
+	timer = logger.TimerD("testtimer", M{"key1": "val1"})
+	buf.Reset()
+	now = now.Add(1250 * time.Microsecond)
+	timer.StopAsGauge()
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Info, "testtimer-end", M{"key1": "val1", "type": "gauge", "value": 1.25}))
+}
+
+func TestLoggingDoesNotModifyData(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewConcreteLogger("logger-tester")
+	logger.SetOutput(buf)
+	logger.AddContext("global", "g")
+	testRouter, err := router.NewFromRoutes(map[string]router.Rule{
+		"all": {
+			Matchers: router.RuleMatchers{"title": []string{"*"}},
+			Output:   router.RuleOutput{"type": "notifications", "channel": "#test"},
+		},
+	})
+	require.NoError(t, err)
+	logger.SetRouter(testRouter)
+
+	data := M{"key1": "val1"}
+	logger.InfoD("first", data)
+	logger.CounterD("second", 2, data)
+	logger.WarnCtx(context.Background(), "third", data)
+	logger.Log(wcl.Info, "fourth", data)
+	assert.Equal(t, M{"key1": "val1"}, data)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	for i, title := range []string{"first", "second", "third", "fourth"} {
+		var line M
+		require.NoError(t, json.Unmarshal([]byte(lines[i]), &line))
+		assert.Equal(t, title, line["title"])
+		assert.Equal(t, "val1", line["key1"])
+		assert.Equal(t, "g", line["global"])
+		assert.Contains(t, line, "_kvmeta")
+	}
+	assert.NotContains(t, lines[3], "value")
+}
+
+// TestConcurrentLoggingWithSharedData is meant to be run with -race.
+func TestConcurrentLoggingWithSharedData(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewConcreteLogger("logger-tester")
+	logger.SetOutput(buf)
+	logger.AddContext("global", "g")
+	testRouter, err := router.NewFromRoutes(map[string]router.Rule{
+		"all": {
+			Matchers: router.RuleMatchers{"title": []string{"*"}},
+			Output:   router.RuleOutput{"type": "notifications", "channel": "#test"},
+		},
+	})
+	require.NoError(t, err)
+	logger.SetRouter(testRouter)
+
+	data := M{"key1": "val1", "nested": M{"key2": 2}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				title := fmt.Sprintf("title-%d", i)
+				logger.InfoD(title, data)
+				logger.CounterD(title, j, data)
+				logger.GaugeFloatD(title, float64(j), data)
+				logger.InfoCtx(context.Background(), title, data)
+				logger.ErrorE(title, fmt.Errorf("failed"), data)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, M{"key1": "val1", "nested": M{"key2": 2}}, data)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 8*50*5)
+	for _, l := range lines {
+		var line M
+		require.NoError(t, json.Unmarshal([]byte(l), &line))
+		assert.Equal(t, "val1", line["key1"])
+		assert.Contains(t, line, "_kvmeta")
+	}
+}
+
+// lockedBuffer is a bytes.Buffer that is safe to write from several log.Loggers at once.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := []string{}
+	for _, line := range strings.Split(b.buf.String(), "\n") {
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// TestReconfigureWhileLogging is meant to be run with -race.
+func TestReconfigureWhileLogging(t *testing.T) {
+	defer storeGlobalRouter(nil)
+	outputs := []*lockedBuffer{{}, {}}
+	logger := NewConcreteLogger("logger-tester")
+	logger.SetOutput(outputs[0])
+	child := logger.With(M{"child": true})
+	testRouter, err := router.NewFromRoutes(map[string]router.Rule{
+		"all": {
+			Matchers: router.RuleMatchers{"title": []string{"*"}},
+			Output:   router.RuleOutput{"type": "notifications", "channel": "#test"},
+		},
+	})
+	require.NoError(t, err)
+	globalRouting := []byte(`
+routes:
+  all:
+    matchers:
+      title: ["*"]
+    output:
+      type: "analytics"
+      series: "global"
+`)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				logger.InfoD("data", M{"key1": "val1"})
+				logger.Info("fields", String("key1", "val1"))
+				logger.CounterD("counter", 1, M{"key1": "val1"})
+				child.WarnD("child", M{"key1": "val1"})
+				logger.InfoCtx(context.Background(), "ctx", M{"key1": "val1"})
+			}
+		}()
+	}
+
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 200; i++ {
+		logger.SetLogLevel([]LogLevel{Trace, Info, Warning}[i%3])
+		logger.SetFormatter([]Formatter{kv.Format, kv.FormatLogfmt}[i%2])
+		logger.SetOutput(outputs[i%2])
+		if i%2 == 0 {
+			logger.SetRouter(testRouter)
+		} else {
+			logger.SetRouter(nil)
+		}
+		if i%4 == 0 {
+			require.NoError(t, SetGlobalRoutingFromBytes(globalRouting))
+		} else {
+			storeGlobalRouter(nil)
+		}
+		require.NoError(t, logger.SetSamplingRules([]SamplingRule{{Title: "data", First: 10}}))
+		logger.SetClock(func() time.Time { return now })
+		logger.SetMetricsOutput(LogMetrics)
+		if i%50 == 0 {
+			require.NoError(t, logger.SetAsync(AsyncConfig{}))
+		}
+		logger.SetConfig("logger-tester", Trace, kv.Format, outputs[i%2])
+	}
+	close(done)
+	wg.Wait()
+	require.NoError(t, logger.Close())
+
+	for _, output := range outputs {
+		lines := output.lines()
+		assert.NotEmpty(t, lines)
+		for _, line := range lines {
+			if json.Valid([]byte(line)) {
+				continue
+			}
+			_, err := kv.ParseLogfmt(line)
+			assert.NoError(t, err, line)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"io"
 	"sync"
 
@@ -82,14 +83,16 @@ func (fl *routeCountingFormatLogger) formatAndLog(data map[string]interface{}) {
 	}
 }
 
-// setFormatter implements the FormatLogger method.
-func (fl *routeCountingFormatLogger) setFormatter(formatter Formatter) {
+// withFormatter implements the FormatLogger method.
+func (fl *routeCountingFormatLogger) withFormatter(formatter Formatter) formatLogger {
 	// we don't format anything in this mock logger
+	return fl
 }
 
-// setOutput implements the FormatLogger method.
-func (fl *routeCountingFormatLogger) setOutput(output io.Writer) {
+// withOutput implements the FormatLogger method.
+func (fl *routeCountingFormatLogger) withOutput(output io.Writer) formatLogger {
 	// we don't output anything in this mock logger
+	return fl
 }
 
 /////////////////////////////////////////////////////////////
@@ -113,6 +116,15 @@ func (ml *MockRouteCountLogger) GetContext(key string) (interface{}, bool) {
 	return ml.logger.GetContext(key)
 }
 
+// With implements the method for the KayveeLogger interface.
+// Route matches of the returned logger are counted together with those of ml.
+func (ml *MockRouteCountLogger) With(fields M) KayveeLogger {
+	return &MockRouteCountLogger{
+		logger:       ml.logger.With(fields),
+		routeMatches: ml.routeMatches,
+	}
+}
+
 // SetLogLevel implements the method for the KayveeLogger interface.
 func (ml *MockRouteCountLogger) SetLogLevel(logLvl LogLevel) {
 	ml.logger.SetLogLevel(logLvl)
@@ -139,33 +151,33 @@ func (ml *MockRouteCountLogger) SetRouter(router router.Router) {
 }
 
 // Trace implements the method for the KayveeLogger interface.
-func (ml *MockRouteCountLogger) Trace(title string) {
-	ml.logger.Trace(title)
+func (ml *MockRouteCountLogger) Trace(title string, fields ...Field) {
+	ml.logger.Trace(title, fields...)
 }
 
 // Debug implements the method for the KayveeLogger interface.
-func (ml *MockRouteCountLogger) Debug(title string) {
-	ml.logger.Debug(title)
+func (ml *MockRouteCountLogger) Debug(title string, fields ...Field) {
+	ml.logger.Debug(title, fields...)
 }
 
 // Info implements the method for the KayveeLogger interface.
-func (ml *MockRouteCountLogger) Info(title string) {
-	ml.logger.Info(title)
+func (ml *MockRouteCountLogger) Info(title string, fields ...Field) {
+	ml.logger.Info(title, fields...)
 }
 
 // Warn implements the method for the KayveeLogger interface.
-func (ml *MockRouteCountLogger) Warn(title string) {
-	ml.logger.Warn(title)
+func (ml *MockRouteCountLogger) Warn(title string, fields ...Field) {
+	ml.logger.Warn(title, fields...)
 }
 
 // Error implements the method for the KayveeLogger interface.
-func (ml *MockRouteCountLogger) Error(title string) {
-	ml.logger.Error(title)
+func (ml *MockRouteCountLogger) Error(title string, fields ...Field) {
+	ml.logger.Error(title, fields...)
 }
 
 // Critical implements the method for the KayveeLogger interface.
-func (ml *MockRouteCountLogger) Critical(title string) {
-	ml.logger.Critical(title)
+func (ml *MockRouteCountLogger) Critical(title string, fields ...Field) {
+	ml.logger.Critical(title, fields...)
 }
 
 // Counter implements the method for the KayveeLogger interface.
@@ -183,6 +195,11 @@ func (ml *MockRouteCountLogger) GaugeFloat(title string, value float64) {
 	ml.logger.GaugeFloat(title, value)
 }
 
+// Histogram implements the method for the KayveeLogger interface.
+func (ml *MockRouteCountLogger) Histogram(title string, value float64) {
+	ml.logger.Histogram(title, value)
+}
+
 // Timer implements the method for the KayveeLogger interface.
 // Returns Timer structure with .Stop method
 func (ml *MockRouteCountLogger) Timer(title string) *Timer {
@@ -219,6 +236,46 @@ func (ml *MockRouteCountLogger) CriticalD(title string, data map[string]interfac
 	ml.logger.CriticalD(title, data)
 }
 
+// ErrorE implements the method for the KayveeLogger interface.
+func (ml *MockRouteCountLogger) ErrorE(title string, err error, data map[string]interface{}) {
+	ml.logger.ErrorE(title, err, data)
+}
+
+// CriticalE implements the method for the KayveeLogger interface.
+func (ml *MockRouteCountLogger) CriticalE(title string, err error, data map[string]interface{}) {
+	ml.logger.CriticalE(title, err, data)
+}
+
+// TraceCtx implements the method for the KayveeLogger interface.
+func (ml *MockRouteCountLogger) TraceCtx(ctx context.Context, title string, data map[string]interface{}) {
+	ml.logger.TraceCtx(ctx, title, data)
+}
+
+// DebugCtx implements the method for the KayveeLogger interface.
+func (ml *MockRouteCountLogger) DebugCtx(ctx context.Context, title string, data map[string]interface{}) {
+	ml.logger.DebugCtx(ctx, title, data)
+}
+
+// InfoCtx implements the method for the KayveeLogger interface.
+func (ml *MockRouteCountLogger) InfoCtx(ctx context.Context, title string, data map[string]interface{}) {
+	ml.logger.InfoCtx(ctx, title, data)
+}
+
+// WarnCtx implements the method for the KayveeLogger interface.
+func (ml *MockRouteCountLogger) WarnCtx(ctx context.Context, title string, data map[string]interface{}) {
+	ml.logger.WarnCtx(ctx, title, data)
+}
+
+// ErrorCtx implements the method for the KayveeLogger interface.
+func (ml *MockRouteCountLogger) ErrorCtx(ctx context.Context, title string, data map[string]interface{}) {
+	ml.logger.ErrorCtx(ctx, title, data)
+}
+
+// CriticalCtx implements the method for the KayveeLogger interface.
+func (ml *MockRouteCountLogger) CriticalCtx(ctx context.Context, title string, data map[string]interface{}) {
+	ml.logger.CriticalCtx(ctx, title, data)
+}
+
 // CounterD implements the method for the KayveeLogger interface.
 func (ml *MockRouteCountLogger) CounterD(title string, value int, data map[string]interface{}) {
 	ml.logger.CounterD(title, value, data)
@@ -235,6 +292,12 @@ func (ml *MockRouteCountLogger) GaugeFloatD(title string, value float64, data ma
 	ml.logger.GaugeFloatD(title, value, data)
 }
 
+// HistogramD implements the method for the KayveeLogger interface.
+// Logs with type = histogram, and value = value
+func (ml *MockRouteCountLogger) HistogramD(title string, value float64, data map[string]interface{}) {
+	ml.logger.HistogramD(title, value, data)
+}
+
 // TimerD implements the method for the KayveeLogger interface.
 // Returns Timer structure with .Stop method
 func (ml *MockRouteCountLogger) TimerD(title string, data map[string]interface{}) *Timer {
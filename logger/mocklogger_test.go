@@ -114,3 +114,27 @@ func TestRouteCountsWithMockLogger(t *testing.T) {
 	actualRoutes2 := mockLogger.RuleOutputs()
 	assert.Equal(t, expectedRoutes2, actualRoutes2)
 }
+
+func TestRouteCountsWithMockLoggerChild(t *testing.T) {
+	testRouter, err := router.NewFromRoutes(map[string]router.Rule{
+		"rule-one": {
+			Matchers: router.RuleMatchers{"foo": []string{"bar"}},
+			Output:   router.RuleOutput{"out": "%{request}"},
+		},
+	})
+	assert.NoError(t, err)
+
+	mockLogger := NewMockCountLogger("testing")
+	mockLogger.SetRouter(testRouter)
+
+	child := mockLogger.With(M{"request": "r1"})
+	child.InfoD("log0", M{"foo": "bar"})
+	mockLogger.InfoD("log1", M{"foo": "bar"})
+
+	assert.Equal(t, map[string][]router.RuleOutput{
+		"rule-one": {
+			router.RuleOutput{"rule": "rule-one", "out": "r1"},
+			router.RuleOutput{"rule": "rule-one", "out": "KEY_NOT_FOUND"},
+		},
+	}, mockLogger.RuleOutputs())
+}
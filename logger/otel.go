@@ -0,0 +1,294 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	kv "github.com/Clever/kayvee-go/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+)
+
+// otlMeterName is the instrumentation scope used for metrics recorded by the logger.
+const otlMeterName = "github.com/Clever/kayvee-go/v7/logger"
+
+var (
+	defaultMeterProviderOnce sync.Once
+	// defaultMeterProvider is set once by getDefaultMeter, and read by FlushOTLMetrics, which
+	// may run concurrently with it.
+	defaultMeterProvider atomic.Pointer[sdkmetric.MeterProvider]
+	defaultMeter         *otlMeter
+)
+
+// otlMeter records kayvee counters, gauges and histograms to OpenTelemetry instruments.
+// Instruments are created on first use of each title and cached.
+type otlMeter struct {
+	meter       metric.Meter
+	mu          sync.RWMutex
+	counters    map[string]metric.Int64UpDownCounter
+	intGauges   map[string]metric.Int64Gauge
+	floatGauges map[string]metric.Float64Gauge
+	histograms  map[string]metric.Float64Histogram
+}
+
+func newOTLMeter(mp metric.MeterProvider) *otlMeter {
+	return &otlMeter{
+		meter:       mp.Meter(otlMeterName, metric.WithInstrumentationVersion(kv.Version)),
+		counters:    map[string]metric.Int64UpDownCounter{},
+		intGauges:   map[string]metric.Int64Gauge{},
+		floatGauges: map[string]metric.Float64Gauge{},
+		histograms:  map[string]metric.Float64Histogram{},
+	}
+}
+
+// SetMeterProvider sets the OpenTelemetry MeterProvider used when metrics are sent to
+// OpenTelemetry (see SetMetricsOutput). By default, a MeterProvider exporting over OTLP/gRPC
+// to the collector at OTEL_COLLECTOR_URL is used.
+func (l *Logger) SetMeterProvider(mp metric.MeterProvider) {
+	meter := newOTLMeter(mp)
+	l.updateSettings(func(s *settings) {
+		s.meter = meter
+	})
+}
+
+// defaultMetricAttributeGlobals are the globals that become metric attributes by default.
+var defaultMetricAttributeGlobals = []string{"source", "deploy_env"}
+
+// SetMetricAttributeGlobals sets which of the logger's globals become attributes of the metrics
+// sent to OpenTelemetry, in addition to the fields passed with each metric. Every distinct set
+// of attribute values is a separate time series, so globals with many values, like IDs, should
+// not be included. Defaults to "source" and "deploy_env". It is shared with child loggers
+// created via With.
+func (l *Logger) SetMetricAttributeGlobals(keys ...string) {
+	globals := append([]string{}, keys...)
+	l.updateSettings(func(s *settings) {
+		s.metricGlobals = globals
+	})
+}
+
+// FlushOTLMetrics exports any metrics recorded through the default MeterProvider that have not
+// been exported yet. It should be called before a process exits.
+func FlushOTLMetrics(ctx context.Context) error {
+	mp := defaultMeterProvider.Load()
+	if mp == nil {
+		return nil
+	}
+	return mp.ForceFlush(ctx)
+}
+
+// getDefaultMeter returns the otlMeter backed by the default MeterProvider, creating it on the
+// first call.
+func getDefaultMeter() *otlMeter {
+	defaultMeterProviderOnce.Do(func() {
+		exporter, err := newOTLMetricExporter(context.Background(), os.Getenv("OTEL_COLLECTOR_URL"))
+		if err != nil {
+			log.Printf("WARN: kayvee logger could not create OTLP metrics exporter: %s", err)
+			defaultMeter = newOTLMeter(noop.NewMeterProvider())
+			return
+		}
+		mp := sdkmetric.NewMeterProvider(
+			sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		)
+		defaultMeterProvider.Store(mp)
+		defaultMeter = newOTLMeter(mp)
+	})
+	return defaultMeter
+}
+
+// newOTLMetricExporter creates an OTLP/gRPC exporter for `collectorURL`, e.g.
+// tcp://localhost:4317. The tcp and http schemes use an insecure connection. If collectorURL is
+// empty, the exporter is configured by the standard OTEL_EXPORTER_OTLP_* environment variables.
+func newOTLMetricExporter(ctx context.Context, collectorURL string) (sdkmetric.Exporter, error) {
+	opts := []otlpmetricgrpc.Option{}
+	if collectorURL != "" {
+		u, err := url.Parse(collectorURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTEL_COLLECTOR_URL '%s': %s", collectorURL, err)
+		}
+		switch u.Scheme {
+		case "tcp", "http":
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(u.Host), otlpmetricgrpc.WithInsecure())
+		case "https":
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(u.Host))
+		default:
+			return nil, fmt.Errorf("unsupported OTEL_COLLECTOR_URL scheme '%s'", u.Scheme)
+		}
+	}
+	return otlpmetricgrpc.New(ctx, opts...)
+}
+
+// recordOTLMetric records the counter, gauge or histogram in `data` to an OpenTelemetry
+// instrument named after its title. Its non-reserved fields and the globals chosen with
+// SetMetricAttributeGlobals become attributes.
+func (l *Logger) recordOTLMetric(data map[string]interface{}) {
+	meter := l.settings.Load().meter
+	if meter == nil {
+		meter = getDefaultMeter()
+	}
+
+	title, _ := data["title"].(string)
+	opt := metric.WithAttributes(l.otlAttributes(data)...)
+	ctx := context.Background()
+
+	var err error
+	switch value := data["value"].(type) {
+	case int:
+		if data["type"] == "counter" {
+			var c metric.Int64UpDownCounter
+			if c, err = meter.counter(title); err == nil {
+				c.Add(ctx, int64(value), opt)
+			}
+		} else {
+			var g metric.Int64Gauge
+			if g, err = meter.intGauge(title); err == nil {
+				g.Record(ctx, int64(value), opt)
+			}
+		}
+	case float64:
+		if data["type"] == "histogram" {
+			var h metric.Float64Histogram
+			if h, err = meter.histogram(title); err == nil {
+				h.Record(ctx, value, opt)
+			}
+		} else {
+			var g metric.Float64Gauge
+			if g, err = meter.floatGauge(title); err == nil {
+				g.Record(ctx, value, opt)
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("WARN: kayvee logger could not record metric '%s': %s", title, err)
+	}
+}
+
+// otlAttributes converts `data` and the globals chosen with SetMetricAttributeGlobals into
+// metric attributes. Values in data override the globals, as they do in log lines, and the
+// logger's redaction policy is applied to the result, so that metrics can't carry what log
+// lines would not.
+func (l *Logger) otlAttributes(data map[string]interface{}) []attribute.KeyValue {
+	globals := l.settings.Load().metricGlobals
+	if globals == nil {
+		globals = defaultMetricAttributeGlobals
+	}
+	merged := map[string]interface{}{}
+	l.globalsL.RLock()
+	for _, k := range globals {
+		if v, ok := l.globals[k]; ok {
+			merged[k] = v
+		}
+	}
+	l.globalsL.RUnlock()
+	for k, v := range data {
+		merged[k] = v
+	}
+	merged = l.redactionPolicy().Apply(merged)
+
+	attrs := make([]attribute.KeyValue, 0, len(merged))
+	for k, v := range merged {
+		if reservedKeyNames[k] && k != "source" {
+			continue
+		}
+		attrs = append(attrs, otlAttribute(k, v))
+	}
+	return attrs
+}
+
+func otlAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case error:
+		return attribute.String(key, v.Error())
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", v))
+	}
+}
+
+func (m *otlMeter) counter(name string) (metric.Int64UpDownCounter, error) {
+	m.mu.RLock()
+	c, ok := m.counters[name]
+	m.mu.RUnlock()
+	if ok {
+		return c, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, err := m.meter.Int64UpDownCounter(name)
+	if err != nil {
+		return nil, err
+	}
+	m.counters[name] = c
+	return c, nil
+}
+
+func (m *otlMeter) intGauge(name string) (metric.Int64Gauge, error) {
+	m.mu.RLock()
+	g, ok := m.intGauges[name]
+	m.mu.RUnlock()
+	if ok {
+		return g, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, err := m.meter.Int64Gauge(name)
+	if err != nil {
+		return nil, err
+	}
+	m.intGauges[name] = g
+	return g, nil
+}
+
+func (m *otlMeter) floatGauge(name string) (metric.Float64Gauge, error) {
+	m.mu.RLock()
+	g, ok := m.floatGauges[name]
+	m.mu.RUnlock()
+	if ok {
+		return g, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	g, err := m.meter.Float64Gauge(name)
+	if err != nil {
+		return nil, err
+	}
+	m.floatGauges[name] = g
+	return g, nil
+}
+
+func (m *otlMeter) histogram(name string) (metric.Float64Histogram, error) {
+	m.mu.RLock()
+	h, ok := m.histograms[name]
+	m.mu.RUnlock()
+	if ok {
+		return h, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, err := m.meter.Float64Histogram(name)
+	if err != nil {
+		return nil, err
+	}
+	m.histograms[name] = h
+	return h, nil
+}
@@ -0,0 +1,276 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/Clever/kayvee-go/v7/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	collectormetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+)
+
+// otlpMetricsReceiver is an in-process stand-in for an OpenTelemetry collector's OTLP/gRPC
+// metrics receiver.
+type otlpMetricsReceiver struct {
+	collectormetricspb.UnimplementedMetricsServiceServer
+	mu      sync.Mutex
+	metrics []*metricspb.Metric
+}
+
+func (r *otlpMetricsReceiver) Export(
+	ctx context.Context, req *collectormetricspb.ExportMetricsServiceRequest,
+) (*collectormetricspb.ExportMetricsServiceResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rm := range req.ResourceMetrics {
+		for _, sm := range rm.ScopeMetrics {
+			r.metrics = append(r.metrics, sm.Metrics...)
+		}
+	}
+	return &collectormetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+// points returns the data points received for the metric `name`, keyed by the value of the
+// "key1" attribute ("" if the attribute is missing).
+func (r *otlpMetricsReceiver) points(name string) map[string]*metricspb.NumberDataPoint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := map[string]*metricspb.NumberDataPoint{}
+	for _, m := range r.metrics {
+		if m.Name != name {
+			continue
+		}
+		var dps []*metricspb.NumberDataPoint
+		if sum := m.GetSum(); sum != nil {
+			dps = sum.DataPoints
+		} else if gauge := m.GetGauge(); gauge != nil {
+			dps = gauge.DataPoints
+		}
+		for _, dp := range dps {
+			out[attributeValue(dp.Attributes, "key1")] = dp
+		}
+	}
+	return out
+}
+
+func attributeValue(attrs []*commonpb.KeyValue, key string) string {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+// newOTLTestLogger returns a logger that records metrics to OpenTelemetry, and a function that
+// flushes them. If OTEL_TEST is set (see test/otelcol-test.sh), metrics go to the collector at
+// OTEL_COLLECTOR_URL and the returned receiver is nil; otherwise they go to an in-process
+// receiver.
+func newOTLTestLogger(t *testing.T, output MetricsOutput) (*Logger, *otlpMetricsReceiver, func()) {
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetMetricsOutput(output)
+	if os.Getenv("OTEL_TEST") != "" {
+		return lg, nil, func() {
+			require.NoError(t, FlushOTLMetrics(context.Background()))
+		}
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	receiver := &otlpMetricsReceiver{}
+	srv := grpc.NewServer()
+	collectormetricspb.RegisterMetricsServiceServer(srv, receiver)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	exporter, err := newOTLMetricExporter(context.Background(), "tcp://"+lis.Addr().String())
+	require.NoError(t, err)
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+	t.Cleanup(func() { mp.Shutdown(context.Background()) })
+	lg.SetMeterProvider(mp)
+
+	return lg, receiver, func() {
+		require.NoError(t, mp.ForceFlush(context.Background()))
+	}
+}
+
+func TestLogOTLCounter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg, receiver, flush := newOTLTestLogger(t, OTLMetrics)
+	lg.SetOutput(buf)
+	lg.Counter("testlogcounter")
+	lg.CounterD("testlogcounter", 2, M{"key1": "val1", "key2": "val2"})
+	flush()
+	assert.Equal(t, "", buf.String(), "OTLMetrics should not write log lines")
+	if receiver == nil {
+		return
+	}
+
+	points := receiver.points("testlogcounter")
+	require.Len(t, points, 2)
+	assert.Equal(t, int64(1), points[""].GetAsInt())
+	assert.Equal(t, int64(2), points["val1"].GetAsInt())
+	assert.Equal(t, "val2", attributeValue(points["val1"].Attributes, "key2"))
+	assert.Equal(t, "logger-tester", attributeValue(points["val1"].Attributes, "source"))
+	assert.Equal(t, "", attributeValue(points["val1"].Attributes, "title"))
+}
+
+func TestLogOTLGaugeInt(t *testing.T) {
+	lg, receiver, flush := newOTLTestLogger(t, OTLMetrics)
+	lg.GaugeInt("testloggaugeint", 0)
+	lg.GaugeIntD("testloggaugeint", 4, M{"key1": "val1", "key2": "val2"})
+	flush()
+	if receiver == nil {
+		return
+	}
+
+	points := receiver.points("testloggaugeint")
+	require.Len(t, points, 2)
+	assert.Equal(t, int64(0), points[""].GetAsInt())
+	assert.Equal(t, int64(4), points["val1"].GetAsInt())
+}
+
+func TestLogOTLGaugeFloat(t *testing.T) {
+	lg, receiver, flush := newOTLTestLogger(t, OTLMetrics)
+	lg.GaugeFloat("testloggaugefloat", 0.0)
+	lg.GaugeFloatD("testloggaugefloat", 4.0, M{"key1": "val1", "key2": "val2"})
+	flush()
+	if receiver == nil {
+		return
+	}
+
+	points := receiver.points("testloggaugefloat")
+	require.Len(t, points, 2)
+	assert.Equal(t, 0.0, points[""].GetAsDouble())
+	assert.Equal(t, 4.0, points["val1"].GetAsDouble())
+}
+
+func TestLogOTLHistogram(t *testing.T) {
+	if os.Getenv("OTEL_TEST") != "" {
+		t.Skip("only runs against the in-process receiver")
+	}
+	lg, receiver, flush := newOTLTestLogger(t, OTLMetrics)
+	lg.HistogramD("testloghistogram", 1.5, M{"key1": "val1"})
+	lg.HistogramD("testloghistogram", 2.5, M{"key1": "val1"})
+	flush()
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	var dps []*metricspb.HistogramDataPoint
+	for _, m := range receiver.metrics {
+		if m.Name == "testloghistogram" {
+			dps = append(dps, m.GetHistogram().DataPoints...)
+		}
+	}
+	require.Len(t, dps, 1)
+	assert.Equal(t, uint64(2), dps[0].Count)
+	assert.Equal(t, 4.0, dps[0].GetSum())
+	assert.Equal(t, "val1", attributeValue(dps[0].Attributes, "key1"))
+}
+
+func TestOTLMetricAttributeGlobals(t *testing.T) {
+	if os.Getenv("OTEL_TEST") != "" {
+		t.Skip("only runs against the in-process receiver")
+	}
+	lg, receiver, flush := newOTLTestLogger(t, OTLMetrics)
+	lg.AddContext("user_id", "u1")
+	lg.AddContext("region", "us-west-1")
+	lg.CounterD("testlogglobals", 1, M{"key1": "default"})
+	lg.SetMetricAttributeGlobals("region")
+	lg.CounterD("testlogglobals", 1, M{"key1": "region"})
+	flush()
+
+	points := receiver.points("testlogglobals")
+	require.Len(t, points, 2)
+	assert.Equal(t, "logger-tester", attributeValue(points["default"].Attributes, "source"))
+	assert.Equal(t, "testing", attributeValue(points["default"].Attributes, "deploy_env"))
+	assert.Equal(t, "", attributeValue(points["default"].Attributes, "user_id"), "only allowed globals are attributes")
+	assert.Equal(t, "", attributeValue(points["default"].Attributes, "region"))
+	assert.Equal(t, "us-west-1", attributeValue(points["region"].Attributes, "region"))
+	assert.Equal(t, "", attributeValue(points["region"].Attributes, "source"))
+}
+
+func TestOTLMetricsAreRedacted(t *testing.T) {
+	if os.Getenv("OTEL_TEST") != "" {
+		t.Skip("only runs against the in-process receiver")
+	}
+	lg, receiver, flush := newOTLTestLogger(t, OTLMetrics)
+	policy, err := redact.New([]redact.Rule{
+		{Keys: []string{"password"}, Action: redact.Drop},
+		{Values: []string{"email"}},
+	})
+	require.NoError(t, err)
+	lg.SetRedactionPolicy(policy)
+	lg.SetMetricAttributeGlobals("password")
+	lg.AddContext("password", "hunter2")
+	lg.CounterD("testlogredacted", 1, M{"key1": "val1", "user": "a@example.com"})
+	flush()
+
+	points := receiver.points("testlogredacted")
+	require.Len(t, points, 1)
+	assert.Equal(t, "[REDACTED]", attributeValue(points["val1"].Attributes, "user"))
+	for _, attr := range points["val1"].Attributes {
+		assert.NotEqual(t, "password", attr.Key)
+	}
+}
+
+func TestLogAndOTLMetrics(t *testing.T) {
+	if os.Getenv("OTEL_TEST") != "" {
+		t.Skip("only runs against the in-process receiver")
+	}
+	buf := &bytes.Buffer{}
+	lg, receiver, flush := newOTLTestLogger(t, LogAndOTLMetrics)
+	lg.SetOutput(buf)
+	lg.GaugeIntD("testloggaugeboth", 3, M{"key1": "val1"})
+	flush()
+
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Info, "testloggaugeboth", M{"key1": "val1", "type": "gauge", "value": 3}))
+	points := receiver.points("testloggaugeboth")
+	require.Len(t, points, 1)
+	assert.Equal(t, int64(3), points["val1"].GetAsInt())
+}
+
+func TestFlushOTLMetricsWhileCreatingDefaultMeter(t *testing.T) {
+	if os.Getenv("OTEL_TEST") != "" {
+		t.Skip("only runs against the in-process receiver")
+	}
+	// nothing listens on this port, so flushes fail fast
+	t.Setenv("OTEL_COLLECTOR_URL", "tcp://127.0.0.1:1")
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetMetricsOutput(OTLMetrics)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lg.Counter("testlogdefaultmeter")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	FlushOTLMetrics(ctx)
+	<-done
+}
+
+func TestMetricsOutputFromEnv(t *testing.T) {
+	t.Setenv("KAYVEE_METRICS_OUTPUT", "both")
+	assert.Equal(t, LogAndOTLMetrics, NewConcreteLogger("logger-tester").settings.Load().metricsOutput)
+	t.Setenv("KAYVEE_METRICS_OUTPUT", "OTL")
+	assert.Equal(t, OTLMetrics, NewConcreteLogger("logger-tester").settings.Load().metricsOutput)
+	t.Setenv("KAYVEE_METRICS_OUTPUT", "")
+	assert.Equal(t, LogMetrics, NewConcreteLogger("logger-tester").settings.Load().metricsOutput)
+}
+
+func TestNewOTLMetricExporterRejectsBadURL(t *testing.T) {
+	_, err := newOTLMetricExporter(context.Background(), "ftp://localhost:4317")
+	assert.Error(t, err)
+}
@@ -0,0 +1,501 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kv "github.com/Clever/kayvee-go/v7"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// OTLPProtocol is an enum used to denote the transport of an OTLPLogsExporter.
+type OTLPProtocol int
+
+// Constants used to define different OTLPProtocols supported
+const (
+	// OTLPGRPC exports over OTLP/gRPC, usually on port 4317
+	OTLPGRPC OTLPProtocol = iota
+	// OTLPHTTP exports protobuf over OTLP/HTTP, usually on port 4318
+	OTLPHTTP
+)
+
+const (
+	defaultOTLPBatchSize     = 512
+	defaultOTLPQueueSize     = 2048
+	defaultOTLPFlushInterval = 5 * time.Second
+	defaultOTLPTimeout       = 10 * time.Second
+	defaultOTLPRetryBackoff  = time.Second
+	otlpLogsHTTPPath         = "/v1/logs"
+	// otlpExportRetries is how many times a failed export is retried before its records are
+	// dropped
+	otlpExportRetries = 2
+)
+
+// otlpSeverities maps LogLevels to OTLP severity numbers.
+var otlpSeverities = map[LogLevel]logspb.SeverityNumber{
+	Trace:    logspb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+	Debug:    logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	Info:     logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	Warning:  logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	Error:    logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	Critical: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+}
+
+// otlpResourceKeys maps the fields of a line that describe the process logging it to the
+// resource attributes they become.
+var otlpResourceKeys = map[string]string{
+	"source":        "service.name",
+	"deploy_env":    "deployment.environment.name",
+	"pod-id":        "pod-id",
+	"pod-shortname": "pod-shortname",
+	"pod-region":    "pod-region",
+	"pod-account":   "pod-account",
+}
+
+// OTLPLogsConfig configures an OTLPLogsExporter. See NewOTLPLogsExporter.
+type OTLPLogsConfig struct {
+	// CollectorURL is the URL of the collector, e.g. tcp://localhost:4317. The tcp and http
+	// schemes use an insecure connection. Defaults to OTEL_COLLECTOR_URL.
+	CollectorURL string
+	// Protocol is the transport records are exported over. Defaults to OTLPGRPC.
+	Protocol OTLPProtocol
+	// Headers are sent with every export, e.g. for authentication.
+	Headers map[string]string
+	// BatchSize is the maximum number of records exported at once. Defaults to 512.
+	BatchSize int
+	// FlushInterval is the longest a record waits before being exported. Defaults to 5 seconds.
+	FlushInterval time.Duration
+	// QueueSize is the maximum number of records waiting to be exported. Records written while
+	// the queue is full are dropped. Defaults to 2048.
+	QueueSize int
+	// Timeout is the timeout of each export. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// otlpEntry is a record waiting to be exported. If flushed is non-nil the entry is a flush
+// marker: it receives the result of exporting every entry queued before it.
+type otlpEntry struct {
+	resource    []*commonpb.KeyValue
+	resourceKey string
+	record      *logspb.LogRecord
+	flushed     chan error
+}
+
+// OTLPLogsExporter is an io.Writer that converts kayvee lines to OTLP log records and exports
+// them in batches to an OpenTelemetry collector:
+//
+//	exp, err := logger.NewOTLPLogsExporter(logger.OTLPLogsConfig{CollectorURL: "tcp://localhost:4317"})
+//	...
+//	defer exp.Close()
+//	lg.SetSinks(logger.Sink{Output: os.Stderr}, logger.Sink{Output: exp})
+//
+// The level of a line becomes the severity of its record and its title the body. Its source,
+// deploy_env and pod metadata become resource attributes, its trace_id and span_id the trace
+// context of the record, and its other fields, except _kvmeta, attributes. Lines that aren't
+// JSON are exported with the line as the body. A failed export is retried twice with backoff
+// before its records are dropped. OTLPLogsExporter is safe for concurrent use.
+type OTLPLogsExporter struct {
+	config  OTLPLogsConfig
+	now     func() time.Time
+	export  func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error
+	conn    *grpc.ClientConn
+	queue   chan otlpEntry
+	dropped int64
+	stopped chan struct{}
+	// retryBackoff is the wait before retrying a failed export, doubled for each retry.
+	retryBackoff time.Duration
+
+	// closedL guards closed, so that no records are queued once the queue has been closed.
+	closedL sync.RWMutex
+	closed  bool
+}
+
+// NewOTLPLogsExporter creates an exporter for the collector at config.CollectorURL, and
+// starts the background goroutine that exports its records.
+func NewOTLPLogsExporter(config OTLPLogsConfig) (*OTLPLogsExporter, error) {
+	if config.CollectorURL == "" {
+		config.CollectorURL = os.Getenv("OTEL_COLLECTOR_URL")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultOTLPBatchSize
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultOTLPQueueSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultOTLPFlushInterval
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultOTLPTimeout
+	}
+	u, err := url.Parse(config.CollectorURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collector URL '%s': %s", config.CollectorURL, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid collector URL '%s': no host", config.CollectorURL)
+	}
+	var secure bool
+	switch u.Scheme {
+	case "tcp", "http":
+	case "https":
+		secure = true
+	default:
+		return nil, fmt.Errorf("unsupported collector URL scheme '%s'", u.Scheme)
+	}
+
+	e := &OTLPLogsExporter{
+		config:  config,
+		now:     time.Now,
+		queue:   make(chan otlpEntry, config.QueueSize),
+		stopped: make(chan struct{}),
+
+		retryBackoff: defaultOTLPRetryBackoff,
+	}
+	switch config.Protocol {
+	case OTLPGRPC:
+		creds := insecure.NewCredentials()
+		if secure {
+			creds = credentials.NewTLS(&tls.Config{})
+		}
+		conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("can't create OTLP logs client: %s", err)
+		}
+		e.conn = conn
+		e.export = e.grpcExporter(collogspb.NewLogsServiceClient(conn))
+	case OTLPHTTP:
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		path := u.Path
+		if path == "" || path == "/" {
+			path = otlpLogsHTTPPath
+		}
+		e.export = e.httpExporter(scheme + "://" + u.Host + path)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %d", config.Protocol)
+	}
+	go e.run()
+	return e, nil
+}
+
+// Write implements io.Writer, queueing a record for each line in p.
+func (e *OTLPLogsExporter) Write(p []byte) (int, error) {
+	e.closedL.RLock()
+	defer e.closedL.RUnlock()
+	if e.closed {
+		return 0, os.ErrClosed
+	}
+	observed := e.now()
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		entry := otlpRecord(line, observed)
+		select {
+		case e.queue <- entry:
+		default:
+			atomic.AddInt64(&e.dropped, 1)
+		}
+	}
+	return len(p), nil
+}
+
+// Flush exports the records written before the call. It returns the first error of the exports
+// since the previous flush, if any failed.
+func (e *OTLPLogsExporter) Flush() error {
+	e.closedL.RLock()
+	if e.closed {
+		e.closedL.RUnlock()
+		return nil
+	}
+	flushed := make(chan error, 1)
+	e.queue <- otlpEntry{flushed: flushed}
+	e.closedL.RUnlock()
+	return <-flushed
+}
+
+// Close exports the queued records and stops the background goroutine.
+func (e *OTLPLogsExporter) Close() error {
+	e.closedL.Lock()
+	if e.closed {
+		e.closedL.Unlock()
+		return nil
+	}
+	e.closed = true
+	close(e.queue)
+	e.closedL.Unlock()
+	<-e.stopped
+
+	if e.conn != nil {
+		return e.conn.Close()
+	}
+	return nil
+}
+
+// run exports queued records in batches until the queue is closed.
+func (e *OTLPLogsExporter) run() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]otlpEntry, 0, e.config.BatchSize)
+	// exportErr is the first export error since the last flush, which returns it
+	var exportErr error
+	export := func() {
+		if err := e.exportBatch(batch); err != nil && exportErr == nil {
+			exportErr = err
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case entry, ok := <-e.queue:
+			if !ok {
+				export()
+				return
+			}
+			if entry.flushed != nil {
+				export()
+				entry.flushed <- exportErr
+				exportErr = nil
+				continue
+			}
+			batch = append(batch, entry)
+			if len(batch) >= e.config.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		}
+	}
+}
+
+// exportBatch exports `batch`, grouping its records by resource.
+func (e *OTLPLogsExporter) exportBatch(batch []otlpEntry) error {
+	if dropped := atomic.SwapInt64(&e.dropped, 0); dropped > 0 {
+		log.Printf("WARN: kayvee OTLP logs exporter dropped %d records because its queue was full", dropped)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+
+	req := &collogspb.ExportLogsServiceRequest{}
+	byResource := map[string]*logspb.ScopeLogs{}
+	for _, entry := range batch {
+		scopeLogs, ok := byResource[entry.resourceKey]
+		if !ok {
+			scopeLogs = &logspb.ScopeLogs{
+				Scope: &commonpb.InstrumentationScope{Name: otlMeterName, Version: kv.Version},
+			}
+			byResource[entry.resourceKey] = scopeLogs
+			req.ResourceLogs = append(req.ResourceLogs, &logspb.ResourceLogs{
+				Resource:  &resourcepb.Resource{Attributes: entry.resource},
+				ScopeLogs: []*logspb.ScopeLogs{scopeLogs},
+			})
+		}
+		scopeLogs.LogRecords = append(scopeLogs.LogRecords, entry.record)
+	}
+
+	backoff := e.retryBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), e.config.Timeout)
+		err := e.export(ctx, req)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt == otlpExportRetries {
+			log.Printf("WARN: kayvee OTLP logs exporter could not export %d records: %s", len(batch), err)
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (e *OTLPLogsExporter) grpcExporter(client collogspb.LogsServiceClient) func(context.Context, *collogspb.ExportLogsServiceRequest) error {
+	return func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+		if len(e.config.Headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.config.Headers))
+		}
+		_, err := client.Export(ctx, req)
+		return err
+	}
+}
+
+func (e *OTLPLogsExporter) httpExporter(endpoint string) func(context.Context, *collogspb.ExportLogsServiceRequest) error {
+	return func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) error {
+		body, err := proto.Marshal(req)
+		if err != nil {
+			return err
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		httpReq.Header.Set("Content-Type", "application/x-protobuf")
+		for k, v := range e.config.Headers {
+			httpReq.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(httpReq)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("collector responded with status %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// otlpRecord converts a log line to a record and the resource it belongs to.
+func otlpRecord(line []byte, observed time.Time) otlpEntry {
+	record := &logspb.LogRecord{ObservedTimeUnixNano: uint64(observed.UnixNano())}
+	data := decodeKayveeLine(line)
+	if data == nil {
+		record.Body = otlpStringValue(string(line))
+		return otlpEntry{record: record}
+	}
+
+	var resource []*commonpb.KeyValue
+	// resourceKey identifies the resource, to group the records of a batch by resource
+	var resourceKey []string
+	for k, v := range data {
+		switch k {
+		case "level":
+			if lvl, ok := v.(string); ok {
+				if logLvl, err := ParseLogLevel(lvl); err == nil {
+					record.SeverityNumber = otlpSeverities[logLvl]
+					record.SeverityText = lvl
+					continue
+				}
+			}
+		case "title":
+			if title, ok := v.(string); ok {
+				record.Body = otlpStringValue(title)
+				continue
+			}
+		case TimestampKey:
+			if t, ok := otlpTimestamp(v); ok {
+				record.TimeUnixNano = uint64(t.UnixNano())
+				continue
+			}
+		case "trace_id":
+			if id, ok := otlpID(v, 16); ok {
+				record.TraceId = id
+				continue
+			}
+		case "span_id":
+			if id, ok := otlpID(v, 8); ok {
+				record.SpanId = id
+				continue
+			}
+		case "_kvmeta":
+			continue
+		}
+		if key, ok := otlpResourceKeys[k]; ok {
+			resource = append(resource, &commonpb.KeyValue{Key: key, Value: otlpValue(v)})
+			resourceKey = append(resourceKey, fmt.Sprintf("%s=%v", key, v))
+			continue
+		}
+		record.Attributes = append(record.Attributes, &commonpb.KeyValue{Key: k, Value: otlpValue(v)})
+	}
+	sortOTLPKeyValues(resource)
+	sortOTLPKeyValues(record.Attributes)
+
+	sort.Strings(resourceKey)
+	return otlpEntry{resource: resource, resourceKey: strings.Join(resourceKey, "\x00"), record: record}
+}
+
+func sortOTLPKeyValues(kvs []*commonpb.KeyValue) {
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Key < kvs[j].Key })
+}
+
+// otlpTimestamp parses a timestamp field, which is either RFC 3339 or milliseconds since the
+// epoch (see SetTimestampFormat).
+func otlpTimestamp(v interface{}) (time.Time, bool) {
+	switch ts := v.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		return t, err == nil
+	case json.Number:
+		ms, err := ts.Int64()
+		return time.UnixMilli(ms), err == nil
+	}
+	return time.Time{}, false
+}
+
+// otlpID decodes a hex trace or span ID of `size` bytes.
+func otlpID(v interface{}, size int) ([]byte, bool) {
+	s, ok := v.(string)
+	if !ok || len(s) != size*2 {
+		return nil, false
+	}
+	id, err := hex.DecodeString(s)
+	return id, err == nil
+}
+
+func otlpStringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+// otlpValue converts a value decoded from a JSON line to an attribute value.
+func otlpValue(value interface{}) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case string:
+		return otlpStringValue(v)
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		}
+		f, _ := v.Float64()
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i, elem := range v {
+			values[i] = otlpValue(elem)
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{
+			ArrayValue: &commonpb.ArrayValue{Values: values},
+		}}
+	case map[string]interface{}:
+		kvs := make([]*commonpb.KeyValue, 0, len(v))
+		for k, elem := range v {
+			kvs = append(kvs, &commonpb.KeyValue{Key: k, Value: otlpValue(elem)})
+		}
+		sortOTLPKeyValues(kvs)
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{
+			KvlistValue: &commonpb.KeyValueList{Values: kvs},
+		}}
+	}
+	return &commonpb.AnyValue{}
+}
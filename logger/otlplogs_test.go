@@ -0,0 +1,263 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// testLogsReceiver records the export requests it receives over gRPC or HTTP.
+type testLogsReceiver struct {
+	collogspb.UnimplementedLogsServiceServer
+	mu      sync.Mutex
+	reqs    []*collogspb.ExportLogsServiceRequest
+	headers []string
+}
+
+func (r *testLogsReceiver) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reqs = append(r.reqs, req)
+	r.headers = append(r.headers, md.Get("x-api-key")...)
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func (r *testLogsReceiver) ServeHTTP(w http.ResponseWriter, httpReq *http.Request) {
+	body, err := io.ReadAll(httpReq.Body)
+	req := &collogspb.ExportLogsServiceRequest{}
+	if err != nil || httpReq.URL.Path != "/v1/logs" || proto.Unmarshal(body, req) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reqs = append(r.reqs, req)
+	r.headers = append(r.headers, httpReq.Header.Values("X-Api-Key")...)
+}
+
+// records returns the records received, keyed by their resource's service.name.
+func (r *testLogsReceiver) records() map[string][]*logspb.LogRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records := map[string][]*logspb.LogRecord{}
+	for _, req := range r.reqs {
+		for _, rl := range req.ResourceLogs {
+			service := otlpAttrs(rl.Resource.Attributes)["service.name"]
+			for _, sl := range rl.ScopeLogs {
+				records[service.GetStringValue()] = append(records[service.GetStringValue()], sl.LogRecords...)
+			}
+		}
+	}
+	return records
+}
+
+func otlpAttrs(kvs []*commonpb.KeyValue) map[string]*commonpb.AnyValue {
+	attrs := map[string]*commonpb.AnyValue{}
+	for _, kv := range kvs {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func startGRPCLogsReceiver(t *testing.T) (*testLogsReceiver, string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	r := &testLogsReceiver{}
+	srv := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(srv, r)
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	return r, "tcp://" + ln.Addr().String()
+}
+
+func TestOTLPLogsExporterGRPC(t *testing.T) {
+	r, url := startGRPCLogsReceiver(t)
+	exp, err := NewOTLPLogsExporter(OTLPLogsConfig{CollectorURL: url, Headers: map[string]string{"x-api-key": "secret"}})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	lg := NewConcreteLoggerWithContext("my-app", M{"pod-region": "us-west-1"})
+	lg.SetOutput(exp)
+	lg.SetTimestampFormat(TimestampRFC3339Nano)
+	lg.SetClock(func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) })
+	lg.WarnD("disk-full", M{"free": 0, "ratio": 0.5, "ok": false, "tags": []string{"a"}, "nested": M{"k": "v"}})
+	lg.ErrorD("failed", M{})
+	require.NoError(t, exp.Flush())
+
+	records := r.records()["my-app"]
+	require.Len(t, records, 2)
+	rec := records[0]
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, rec.SeverityNumber)
+	assert.Equal(t, "warning", rec.SeverityText)
+	assert.Equal(t, "disk-full", rec.Body.GetStringValue())
+	assert.Equal(t, uint64(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()), rec.TimeUnixNano)
+	assert.NotZero(t, rec.ObservedTimeUnixNano)
+
+	attrs := otlpAttrs(rec.Attributes)
+	assert.Equal(t, int64(0), attrs["free"].GetIntValue())
+	assert.Equal(t, 0.5, attrs["ratio"].GetDoubleValue())
+	assert.Equal(t, false, attrs["ok"].GetBoolValue())
+	assert.Equal(t, "a", attrs["tags"].GetArrayValue().Values[0].GetStringValue())
+	assert.Equal(t, "v", otlpAttrs(attrs["nested"].GetKvlistValue().Values)["k"].GetStringValue())
+	for _, key := range []string{"level", "title", "source", "pod-region", TimestampKey} {
+		assert.NotContains(t, attrs, key)
+	}
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, records[1].SeverityNumber)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	require.Len(t, r.reqs, 1, "both records are exported in one batch")
+	resource := otlpAttrs(r.reqs[0].ResourceLogs[0].Resource.Attributes)
+	assert.Equal(t, "us-west-1", resource["pod-region"].GetStringValue())
+	assert.Equal(t, []string{"secret"}, r.headers)
+}
+
+func TestOTLPLogsExporterTraceContext(t *testing.T) {
+	r, url := startGRPCLogsReceiver(t)
+	exp, err := NewOTLPLogsExporter(OTLPLogsConfig{CollectorURL: url})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	lg := NewConcreteLogger("my-app")
+	lg.SetOutput(exp)
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	lg.InfoCtx(ctx, "traced", M{})
+	require.NoError(t, exp.Flush())
+
+	records := r.records()["my-app"]
+	require.Len(t, records, 1)
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", hex.EncodeToString(records[0].TraceId))
+	assert.Equal(t, "0102030405060708", hex.EncodeToString(records[0].SpanId))
+	assert.NotContains(t, otlpAttrs(records[0].Attributes), "trace_id")
+}
+
+func TestOTLPLogsExporterHTTP(t *testing.T) {
+	r := &testLogsReceiver{}
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	exp, err := NewOTLPLogsExporter(OTLPLogsConfig{
+		CollectorURL: srv.URL,
+		Protocol:     OTLPHTTP,
+		BatchSize:    2,
+		Headers:      map[string]string{"X-Api-Key": "secret"},
+	})
+	require.NoError(t, err)
+	lg := NewConcreteLogger("my-app")
+	lg.SetOutput(exp)
+	for i := 0; i < 5; i++ {
+		lg.InfoD("line", M{"i": i})
+	}
+	_, err = exp.Write([]byte("not json\n"))
+	require.NoError(t, err)
+	require.NoError(t, exp.Close())
+
+	records := r.records()
+	require.Len(t, records["my-app"], 5)
+	for i, rec := range records["my-app"] {
+		assert.Equal(t, int64(i), otlpAttrs(rec.Attributes)["i"].GetIntValue())
+	}
+	require.Len(t, records[""], 1)
+	assert.Equal(t, "not json", records[""][0].Body.GetStringValue())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	assert.Len(t, r.reqs, 3, "the records are exported in batches of 2")
+	assert.Equal(t, []string{"secret", "secret", "secret"}, r.headers)
+
+	_, err = exp.Write([]byte("closed\n"))
+	assert.Error(t, err)
+}
+
+func TestOTLPLogsExporterErrors(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	exp, err := NewOTLPLogsExporter(OTLPLogsConfig{CollectorURL: srv.URL, Protocol: OTLPHTTP})
+	require.NoError(t, err)
+	defer exp.Close()
+	exp.retryBackoff = time.Millisecond
+	_, err = exp.Write([]byte(`{"level":"info","title":"hello"}` + "\n"))
+	require.NoError(t, err)
+	assert.Error(t, exp.Flush())
+	assert.NoError(t, exp.Flush(), "errors are only returned by the next flush")
+	assert.Equal(t, int32(1+otlpExportRetries), requests.Load())
+
+	_, err = NewOTLPLogsExporter(OTLPLogsConfig{CollectorURL: "ftp://localhost:4317"})
+	assert.Error(t, err)
+	_, err = NewOTLPLogsExporter(OTLPLogsConfig{CollectorURL: "localhost"})
+	assert.Error(t, err)
+}
+
+func TestOTLPLogsExporterRetries(t *testing.T) {
+	r := &testLogsReceiver{}
+	var failed atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, httpReq *http.Request) {
+		if failed.CompareAndSwap(false, true) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		r.ServeHTTP(w, httpReq)
+	}))
+	defer srv.Close()
+
+	exp, err := NewOTLPLogsExporter(OTLPLogsConfig{CollectorURL: srv.URL, Protocol: OTLPHTTP})
+	require.NoError(t, err)
+	defer exp.Close()
+	exp.retryBackoff = time.Millisecond
+	lg := NewConcreteLogger("my-app")
+	lg.SetOutput(exp)
+	lg.Info("hello")
+	require.NoError(t, exp.Flush())
+	assert.Len(t, r.records()["my-app"], 1)
+}
+
+func TestLoggerFlushesOTLPLogsExporter(t *testing.T) {
+	r, url := startGRPCLogsReceiver(t)
+	exp, err := NewOTLPLogsExporter(OTLPLogsConfig{CollectorURL: url})
+	require.NoError(t, err)
+	defer exp.Close()
+
+	lg := NewConcreteLogger("my-app")
+	require.NoError(t, lg.SetSinks(Sink{Output: &bytes.Buffer{}}, Sink{Output: exp}))
+	require.NoError(t, lg.SetAsync(AsyncConfig{}))
+	lg.Info("hello")
+	lg.InfoD("hello", M{"source": "other-app"})
+	lg.Flush()
+	records := r.records()
+	assert.Len(t, records["my-app"], 1, "Flush exports the batched records")
+	assert.Len(t, records["other-app"], 1)
+
+	require.NoError(t, lg.Close())
+	_, err = exp.Write([]byte("still open\n"))
+	assert.NoError(t, err, "Close leaves the exporter to the caller")
+}
@@ -0,0 +1,240 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	kv "github.com/Clever/kayvee-go/v7"
+)
+
+const (
+	prettySourceWidth = 16
+	prettyTitleWidth  = 24
+	prettyTimeLayout  = "15:04:05.000"
+)
+
+var prettyLevelColors = map[string]string{
+	"trace":    "\x1b[90m",
+	"debug":    "\x1b[36m",
+	"info":     "\x1b[32m",
+	"warning":  "\x1b[33m",
+	"error":    "\x1b[31m",
+	"critical": "\x1b[1;31m",
+}
+
+const (
+	colorReset = "\x1b[0m"
+	colorDim   = "\x1b[2m"
+)
+
+// prettyHeaderKeys are the fields printed in the columns before the other fields.
+var prettyHeaderKeys = map[string]bool{
+	"level":      true,
+	"source":     true,
+	"title":      true,
+	"_kvmeta":    true,
+	TimestampKey: true,
+}
+
+// PrettyFormatter is a Formatter for reading logs in a terminal during local development. It
+// prints the time, a colored level, the source and the title in aligned columns, followed by the
+// other fields sorted as key=value pairs, with nested maps indented on the lines below. Routing
+// metadata is summarized as the names of the rules the line matched.
+var PrettyFormatter = NewPrettyFormatter(true)
+
+// NewPrettyFormatter returns a Formatter like PrettyFormatter, with or without ANSI colors.
+func NewPrettyFormatter(color bool) Formatter {
+	return func(data map[string]interface{}) string {
+		return formatPretty(data, color)
+	}
+}
+
+func formatPretty(data map[string]interface{}, color bool) string {
+	var sb strings.Builder
+
+	level, _ := data["level"].(string)
+	source := fmt.Sprintf("%v", valueOrEmpty(data["source"]))
+	title := fmt.Sprintf("%v", valueOrEmpty(data["title"]))
+	levelCol := fmt.Sprintf("%-8s", strings.ToUpper(level))
+	timeCol := prettyTime(data[TimestampKey]).Format(prettyTimeLayout)
+	if color {
+		timeCol = colorDim + timeCol + colorReset
+		if c, ok := prettyLevelColors[level]; ok {
+			levelCol = c + levelCol + colorReset
+		}
+	}
+	fmt.Fprintf(&sb, "%s %s %-*s %-*s", timeCol, levelCol,
+		prettySourceWidth, source, prettyTitleWidth, title)
+
+	nested := writePrettyFields(&sb, data, prettyHeaderKeys, color)
+	if rules := matchedRules(data["_kvmeta"]); len(rules) > 0 {
+		key := "routes"
+		if color {
+			key = colorDim + key + colorReset
+		}
+		fmt.Fprintf(&sb, " %s=[%s]", key, strings.Join(rules, ","))
+	}
+	writePrettyNested(&sb, nested, 1, color)
+	return strings.TrimRight(sb.String(), " ")
+}
+
+// writePrettyFields writes the fields of m not in `skip` as sorted key=value pairs, and
+// returns the keys of the fields that are maps, to be written on their own lines.
+func writePrettyFields(sb *strings.Builder, m map[string]interface{}, skip map[string]bool, color bool) []prettyNested {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if !skip[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var nested []prettyNested
+	for _, k := range keys {
+		if sub, ok := asStringMap(m[k]); ok && len(sub) > 0 {
+			nested = append(nested, prettyNested{key: k, value: sub})
+			continue
+		}
+		key := k
+		if color {
+			key = colorDim + k + colorReset
+		}
+		fmt.Fprintf(sb, " %s=%s", key, prettyValue(m[k]))
+	}
+	return nested
+}
+
+type prettyNested struct {
+	key   string
+	value map[string]interface{}
+}
+
+func writePrettyNested(sb *strings.Builder, nested []prettyNested, depth int, color bool) {
+	indent := strings.Repeat("    ", depth)
+	for _, n := range nested {
+		fmt.Fprintf(sb, "\n%s%s:", indent, n.key)
+		inner := writePrettyFields(sb, n.value, nil, color)
+		writePrettyNested(sb, inner, depth+1, color)
+	}
+}
+
+// prettyValue formats a scalar or slice value, quoting strings that would be ambiguous.
+func prettyValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		if val == "" || strings.ContainsAny(val, " \t\n\"=") {
+			return strconv.Quote(val)
+		}
+		return val
+	case error:
+		return strconv.Quote(val.Error())
+	case fmt.Stringer:
+		return prettyValue(val.String())
+	case nil:
+		return "null"
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Struct, reflect.Ptr:
+		if bs, err := json.Marshal(v); err == nil {
+			return string(bs)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// matchedRules returns the names of the routing rules in the `_kvmeta` field.
+func matchedRules(kvmeta interface{}) []string {
+	meta, ok := asStringMap(kvmeta)
+	if !ok {
+		return nil
+	}
+	var routes []interface{}
+	switch r := meta["routes"].(type) {
+	case []map[string]interface{}:
+		for _, route := range r {
+			routes = append(routes, route)
+		}
+	case []interface{}:
+		routes = r
+	}
+	names := []string{}
+	for _, route := range routes {
+		if m, ok := asStringMap(route); ok {
+			if name, ok := m["rule"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// asStringMap returns v as a map[string]interface{} if it is a map with string keys, like M.
+func asStringMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case M:
+		return m, true
+	}
+	return nil, false
+}
+
+// prettyTime returns the time of the line from its timestamp field, if it has one in a format
+// the logger writes, or the current time.
+func prettyTime(ts interface{}) time.Time {
+	switch t := ts.(type) {
+	case string:
+		if parsed, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return parsed.Local()
+		}
+	case int64:
+		return time.UnixMilli(t)
+	case float64:
+		return time.UnixMilli(int64(t))
+	}
+	return time.Now()
+}
+
+func valueOrEmpty(v interface{}) interface{} {
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+// isTerminal returns whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// stderrIsTerminal returns whether stderr is a terminal. It is a variable so tests can fake one.
+var stderrIsTerminal = func() bool { return isTerminal(os.Stderr) }
+
+// formatterFromEnv returns the Formatter selected by KAYVEE_FORMAT: "pretty", "json" or
+// "logfmt". If it is not set, PrettyFormatter is used when stderr is a terminal, and kv.Format
+// otherwise; `tty` is whether PrettyFormatter was picked for that reason, in which case it is
+// only used while the output is stderr. Colors are only used on a terminal, and can be turned
+// off by setting NO_COLOR.
+func formatterFromEnv(format string, defaultFormatter Formatter) (formatter Formatter, tty bool) {
+	isTTY := stderrIsTerminal()
+	color := isTTY && os.Getenv("NO_COLOR") == ""
+	switch strings.ToLower(format) {
+	case "pretty":
+		return NewPrettyFormatter(color), false
+	case "json":
+		return defaultFormatter, false
+	case "logfmt":
+		return kv.FormatLogfmt, false
+	}
+	if isTTY {
+		return NewPrettyFormatter(color), true
+	}
+	return defaultFormatter, false
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/Clever/kayvee-go/v7/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrettyFormatter(t *testing.T) {
+	ts := time.Date(2026, 10, 16, 4, 45, 6, 123000000, time.UTC)
+	line := NewPrettyFormatter(false)(map[string]interface{}{
+		"level":      "warning",
+		"source":     "my-app",
+		"title":      "request-finished",
+		TimestampKey: ts.Format(time.RFC3339Nano),
+		"status":     200,
+		"path":       "/students",
+		"note":       "two words",
+		"ids":        []int{1, 2},
+		"headers":    M{"accept": "json", "extra": map[string]interface{}{"a": "b"}},
+		"_kvmeta": map[string]interface{}{
+			"team": "eng",
+			"routes": []map[string]interface{}{
+				{"rule": "slow-requests", "type": "metrics"},
+				{"rule": "notify", "type": "notifications"},
+			},
+		},
+	})
+
+	expected := fmt.Sprintf("%s WARNING  my-app           request-finished        "+
+		" ids=[1,2] note=\"two words\" path=/students status=200 routes=[slow-requests,notify]\n"+
+		"    headers: accept=json\n"+
+		"        extra: a=b", ts.Local().Format(prettyTimeLayout))
+	assert.Equal(t, expected, line)
+}
+
+func TestPrettyFormatterColors(t *testing.T) {
+	line := PrettyFormatter(map[string]interface{}{"level": "error", "source": "s", "title": "t", "k": "v"})
+	assert.Contains(t, line, "\x1b[31mERROR   \x1b[0m")
+	assert.Contains(t, line, "\x1b[2mk\x1b[0m=v")
+	assert.NotContains(t, NewPrettyFormatter(false)(map[string]interface{}{"level": "error"}), "\x1b[")
+}
+
+func TestPrettyFormatterWithLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetConfig("logger-tester", Trace, NewPrettyFormatter(false), buf)
+	lg.SetTimestampFormat(TimestampEpochMillis)
+	lg.SetClock(func() time.Time { return time.UnixMilli(1760590000123) })
+	testRouter, err := router.NewFromRoutes(map[string]router.Rule{
+		"my-rule": {
+			Matchers: router.RuleMatchers{"title": []string{"hello"}},
+			Output:   router.RuleOutput{"type": "analytics", "series": "s"},
+		},
+	})
+	require.NoError(t, err)
+	lg.SetRouter(testRouter)
+
+	lg.InfoD("hello", M{"n": 1})
+	out := strings.TrimSpace(buf.String())
+	assert.True(t, strings.HasPrefix(out, time.UnixMilli(1760590000123).Format(prettyTimeLayout)+" INFO "), out)
+	assert.Contains(t, out, " n=1")
+	assert.Contains(t, out, " routes=[my-rule]")
+	assert.NotContains(t, out, "_kvmeta")
+}
+
+func TestFormatterFromEnv(t *testing.T) {
+	// stderr is not a terminal under go test
+	sample := map[string]interface{}{"level": "info", "title": "t"}
+	assert.Equal(t, kv.Format(M{"level": "info", "title": "t"}), formatterFromEnvFormat("")(sample))
+	assert.Equal(t, kv.Format(M{"level": "info", "title": "t"}), formatterFromEnvFormat("json")(sample))
+	assert.Equal(t, NewPrettyFormatter(false)(sample), formatterFromEnvFormat("PRETTY")(sample))
+	assert.Equal(t, kv.FormatLogfmt(M{"level": "info", "title": "t"}), formatterFromEnvFormat("logfmt")(sample))
+
+	t.Setenv("KAYVEE_FORMAT", "pretty")
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	lg.Info("hello")
+	assert.Contains(t, buf.String(), " INFO     logger-tester    hello")
+}
+
+// formatterFromEnvFormat returns the formatter picked by formatterFromEnv for `format`.
+func formatterFromEnvFormat(format string) Formatter {
+	formatter, _ := formatterFromEnv(format, kv.Format)
+	return formatter
+}
+
+func TestTTYFormatterOnlyWritesToStderr(t *testing.T) {
+	defer func(f func() bool) { stderrIsTerminal = f }(stderrIsTerminal)
+	stderrIsTerminal = func() bool { return true }
+	t.Setenv("NO_COLOR", "1")
+	sample := map[string]interface{}{"level": "info", "title": "t"}
+
+	lg := NewConcreteLogger("logger-tester")
+	assert.Equal(t, NewPrettyFormatter(false)(sample), lg.settings.Load().fLogger.(*defaultFormatLogger).format(sample))
+
+	// any other output gets JSON lines, e.g. files and collectors that parse them
+	buf := &bytes.Buffer{}
+	lg.SetOutput(buf)
+	lg.Info("hello")
+	assert.Equal(t, []string{"hello"}, loggedTitles(t, buf))
+
+	// the pretty formatter is kept when it is asked for explicitly
+	t.Setenv("KAYVEE_FORMAT", "pretty")
+	lg = NewConcreteLogger("logger-tester")
+	buf.Reset()
+	lg.SetOutput(buf)
+	lg.Info("hello")
+	assert.Contains(t, buf.String(), " INFO     logger-tester    hello")
+}
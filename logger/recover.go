@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+)
+
+// PanicTitle is the title the Go function logs recovered panics with.
+const PanicTitle = "panic"
+
+// Recover logs a panic in the calling goroutine, as described in LogPanic, then panics again
+// with the same value. Since the process is about to crash, outputs of lg that can only be
+// flushed by closing them, like the analytics and kinesisstream loggers, are closed. It must be
+// called directly by defer:
+//
+//	defer logger.Recover(lg, "worker-panic")
+func Recover(lg KayveeLogger, title string) {
+	if r := recover(); r != nil {
+		LogPanic(lg, title, r, debug.Stack())
+		if _, ok := lg.(flusher); !ok {
+			if closer, ok := lg.(io.Closer); ok {
+				closer.Close()
+			}
+		}
+		panic(r)
+	}
+}
+
+// Go runs fn in a new goroutine, logging any panic with the title PanicTitle before it crashes
+// the process. See Recover.
+func Go(lg KayveeLogger, fn func()) {
+	go func() {
+		defer Recover(lg, PanicTitle)
+		fn()
+	}()
+}
+
+// LogPanic logs a panic value recovered with recover() at Critical, with the value under
+// "panic", its type under "panic_type" and `stack` (as returned by debug.Stack) under "stack".
+// If lg buffers lines, like an asynchronous Logger or one writing to an OTLPLogsExporter, they
+// are flushed.
+func LogPanic(lg KayveeLogger, title string, value interface{}, stack []byte) {
+	data := M{
+		"panic":      fmt.Sprintf("%v", value),
+		"panic_type": fmt.Sprintf("%T", value),
+		"stack":      string(stack),
+	}
+	if err, ok := value.(error); ok {
+		data["panic"] = err.Error()
+	}
+	lg.CriticalD(title, data)
+	if f, ok := lg.(flusher); ok {
+		f.Flush()
+	}
+}
+
+// flusher is implemented by loggers that buffer lines, like *Logger.
+type flusher interface {
+	Flush()
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// closeRecorder is a KayveeLogger that can only be flushed by closing it, like the analytics
+// logger.
+type closeRecorder struct {
+	KayveeLogger
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	w := newGatedWriter()
+	w.release()
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(w)
+	require.NoError(t, lg.SetAsync(AsyncConfig{}))
+	defer lg.Close()
+
+	assert.PanicsWithValue(t, "boom", func() {
+		defer Recover(lg, "worker-panic")
+		panic("boom")
+	})
+
+	lines := decodeLines(t, &w.buf)
+	require.Len(t, lines, 1, "the async logger should be flushed before re-panicking")
+	assert.Equal(t, "critical", lines[0]["level"])
+	assert.Equal(t, "worker-panic", lines[0]["title"])
+	assert.Equal(t, "boom", lines[0]["panic"])
+	assert.Equal(t, "string", lines[0]["panic_type"])
+	assert.Contains(t, lines[0]["stack"], "logger.TestRecover")
+}
+
+func TestRecoverNoPanic(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	assert.NotPanics(t, func() {
+		defer Recover(lg, "worker-panic")
+	})
+	assert.Empty(t, buf.String())
+}
+
+func TestRecoverClosesUnflushableLoggers(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	cr := &closeRecorder{KayveeLogger: lg}
+
+	err := errors.New("boom")
+	assert.PanicsWithValue(t, err, func() {
+		defer Recover(cr, "worker-panic")
+		panic(err)
+	})
+	assert.True(t, cr.closed)
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "boom", lines[0]["panic"])
+	assert.Equal(t, "*errors.errorString", lines[0]["panic_type"])
+}
+
+func TestGo(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	done := make(chan struct{})
+	Go(lg, func() {
+		defer close(done)
+		lg.Info("in-goroutine")
+	})
+	<-done
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "in-goroutine", lines[0]["title"])
+}
@@ -0,0 +1,42 @@
+package logger
+
+import (
+	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/Clever/kayvee-go/v7/redact"
+)
+
+// SetRedactionPolicy sets the redaction policy the logger and its children apply to every
+// line, including its globals, before it is routed and formatted. By default, loggers use the
+// policy set with kv.SetRedactionPolicy. Passing nil reverts to that default.
+func (l *Logger) SetRedactionPolicy(p *redact.Policy) {
+	l.redaction.Store(p)
+}
+
+// SetRedactionFromConfig sets the redaction policy of the logger from the `redaction` section
+// of the yaml file `filename`. See redact.FromConfig.
+func (l *Logger) SetRedactionFromConfig(filename string) error {
+	p, err := redact.FromConfig(filename)
+	if err != nil {
+		return err
+	}
+	l.SetRedactionPolicy(p)
+	return nil
+}
+
+// SetRedactionFromConfigBytes is like SetRedactionFromConfig, with the yaml configuration
+// passed as bytes.
+func (l *Logger) SetRedactionFromConfigBytes(fileBytes []byte) error {
+	p, err := redact.FromConfigBytes(fileBytes)
+	if err != nil {
+		return err
+	}
+	l.SetRedactionPolicy(p)
+	return nil
+}
+
+func (l *Logger) redactionPolicy() *redact.Policy {
+	if p := l.redaction.Load(); p != nil {
+		return p
+	}
+	return kv.RedactionPolicy()
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/Clever/kayvee-go/v7/redact"
+	"github.com/Clever/kayvee-go/v7/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactionBeforeRouting(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLoggerWithContext("logger-tester", M{"api_key": "global-secret"})
+	lg.SetOutput(buf)
+	testRouter, err := router.NewFromRoutes(map[string]router.Rule{
+		"notify": {
+			Matchers: router.RuleMatchers{"title": []string{"login"}},
+			Output: router.RuleOutput{
+				"type":    "notifications",
+				"channel": "#alerts",
+				"icon":    ":key:",
+				"message": "login by %{email}",
+				"user":    "kayvee",
+			},
+		},
+	})
+	require.NoError(t, err)
+	lg.SetRouter(testRouter)
+
+	p, err := redact.New([]redact.Rule{
+		{Keys: []string{"api_key"}, Action: redact.Drop},
+		{Values: []string{"email"}},
+	})
+	require.NoError(t, err)
+	lg.SetRedactionPolicy(p)
+
+	data := M{"email": "student@example.com"}
+	lg.InfoD("login", data)
+	assert.Equal(t, "student@example.com", data["email"], "caller's value should not be redacted")
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "[REDACTED]", lines[0]["email"])
+	assert.NotContains(t, lines[0], "api_key")
+	routes := lines[0]["_kvmeta"].(map[string]interface{})["routes"].([]interface{})
+	require.Len(t, routes, 1)
+	assert.Equal(t, "login by [REDACTED]", routes[0].(map[string]interface{})["message"])
+}
+
+func TestRedactionDefaultsToGlobalPolicy(t *testing.T) {
+	p, err := redact.New([]redact.Rule{{Keys: []string{"password"}}})
+	require.NoError(t, err)
+	kv.SetRedactionPolicy(p)
+	defer kv.SetRedactionPolicy(nil)
+
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	lg.InfoD("login", M{"password": "hunter2"})
+
+	own, err := redact.New([]redact.Rule{{Keys: []string{"password"}, Action: redact.Drop}})
+	require.NoError(t, err)
+	lg.With(M{"a": "b"}).(*Logger).SetRedactionPolicy(own)
+	lg.InfoD("login", M{"password": "hunter2"})
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "[REDACTED]", lines[0]["password"])
+	assert.NotContains(t, lines[1], "password")
+}
+
+func TestSetRedactionFromConfig(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	require.NoError(t, lg.SetRedactionFromConfigBytes([]byte(`
+redaction:
+  - keys: [Authorization]
+    action: hash
+`)))
+	lg.InfoD("request", M{"headers": M{"authorization": "Bearer abc"}})
+	headers := decodeLines(t, buf)[0]["headers"].(map[string]interface{})
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, headers["authorization"])
+
+	assert.Error(t, lg.SetRedactionFromConfigBytes([]byte("redaction:\n  - action: drop\n")))
+	assert.Error(t, lg.SetRedactionFromConfig("/does/not/exist.yml"))
+}
@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// sampleRateKey is added to lines logged after a sampling rule's burst, with the number of
+	// lines each logged line stands for, so downstream metrics can re-weight them.
+	sampleRateKey = "sample_rate"
+
+	// samplingAllTitles is the title of a sampling rule that applies to every title without a
+	// rule of its own.
+	samplingAllTitles = "*"
+
+	defaultSamplingInterval = time.Second
+
+	// maxSamplingKeys bounds the number of sampling counters kept before expired ones are
+	// pruned.
+	maxSamplingKeys = 10000
+)
+
+// SamplingRule limits how many lines with a given title are logged.
+// In each Interval, the First lines are logged, and after that one in every Thereafter lines is
+// logged with a `sample_rate` field set to Thereafter. If Thereafter is 0, no further lines are
+// logged until the next interval.
+type SamplingRule struct {
+	// Title is the title of the lines to sample, or "*" for all titles without their own rule.
+	Title string `yaml:"title"`
+	// KeyFields are fields whose values are also used to group lines. Lines with the same title
+	// but different values for these fields are counted separately.
+	KeyFields []string `yaml:"key_fields"`
+	// First is the number of lines logged in each interval before sampling starts.
+	First int `yaml:"first"`
+	// Thereafter is the sampling rate once First lines have been logged in an interval.
+	Thereafter int `yaml:"thereafter"`
+	// Interval is how often the counts are reset. Defaults to one second.
+	Interval time.Duration `yaml:"interval"`
+	// SampleErrors applies the rule to Error and Critical lines too. By default they are
+	// always logged.
+	SampleErrors bool `yaml:"sample_errors"`
+}
+
+// sampler decides which lines are logged according to a set of SamplingRules.
+type sampler struct {
+	rules map[string]SamplingRule
+
+	mu       sync.Mutex
+	counters map[string]*samplingCounter
+}
+
+type samplingCounter struct {
+	resetAt time.Time
+	count   int
+}
+
+// SetSamplingRules replaces the sampling rules of the logger and its children. Passing no
+// rules turns sampling off.
+func (l *Logger) SetSamplingRules(rules []SamplingRule) error {
+	if len(rules) == 0 {
+		l.updateSettings(func(s *settings) {
+			s.sampler = nil
+		})
+		return nil
+	}
+	smp, err := newSampler(rules)
+	if err != nil {
+		return err
+	}
+	l.updateSettings(func(s *settings) {
+		s.sampler = smp
+	})
+	return nil
+}
+
+// SetSamplingFromConfig sets the sampling rules of the logger from the `sampling` section of
+// the yaml file `filename`, e.g.
+//
+//	sampling:
+//	  - title: db-query
+//	    key_fields: [table]
+//	    first: 100
+//	    thereafter: 10
+//	    interval: 1s
+func (l *Logger) SetSamplingFromConfig(filename string) error {
+	fileBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := l.SetSamplingFromConfigBytes(fileBytes); err != nil {
+		return fmt.Errorf("Error initializing kayvee log sampling from file '%s':\n%s", filename, err.Error())
+	}
+	return nil
+}
+
+// SetSamplingFromConfigBytes is like SetSamplingFromConfig, with the yaml configuration
+// passed as bytes.
+func (l *Logger) SetSamplingFromConfigBytes(fileBytes []byte) error {
+	var config struct {
+		Sampling []SamplingRule `yaml:"sampling"`
+	}
+	if err := yaml.Unmarshal(fileBytes, &config); err != nil {
+		return err
+	}
+	return l.SetSamplingRules(config.Sampling)
+}
+
+func newSampler(rules []SamplingRule) (*sampler, error) {
+	s := &sampler{
+		rules:    map[string]SamplingRule{},
+		counters: map[string]*samplingCounter{},
+	}
+	for _, rule := range rules {
+		if rule.Title == "" {
+			return nil, fmt.Errorf("sampling rule is missing a title")
+		}
+		if rule.First < 0 || rule.Thereafter < 0 {
+			return nil, fmt.Errorf("sampling rule for '%s' has a negative first or thereafter", rule.Title)
+		}
+		if _, ok := s.rules[rule.Title]; ok {
+			return nil, fmt.Errorf("duplicate sampling rule for '%s'", rule.Title)
+		}
+		if rule.Interval <= 0 {
+			rule.Interval = defaultSamplingInterval
+		}
+		s.rules[rule.Title] = rule
+	}
+	return s, nil
+}
+
+// sample returns whether the line in data, logged at now, should be logged, and the sample
+// rate to log it with (0 if the line is not sampled).
+func (s *sampler) sample(now time.Time, logLvl LogLevel, data map[string]interface{}) (bool, int) {
+	title, _ := data["title"].(string)
+	rule, ok := s.rules[title]
+	if !ok {
+		if rule, ok = s.rules[samplingAllTitles]; !ok {
+			return true, 0
+		}
+	}
+	if logLvl >= Error && !rule.SampleErrors {
+		return true, 0
+	}
+
+	key := title
+	if len(rule.KeyFields) > 0 {
+		var sb strings.Builder
+		sb.WriteString(title)
+		for _, field := range rule.KeyFields {
+			fmt.Fprintf(&sb, "\x00%v", data[field])
+		}
+		key = sb.String()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.counters[key]
+	if !ok {
+		if len(s.counters) >= maxSamplingKeys {
+			s.pruneExpired(now)
+		}
+		c = &samplingCounter{}
+		s.counters[key] = c
+	}
+	if !now.Before(c.resetAt) {
+		c.resetAt = now.Add(rule.Interval)
+		c.count = 0
+	}
+	c.count++
+
+	if c.count <= rule.First {
+		return true, 0
+	}
+	if rule.Thereafter > 0 && (c.count-rule.First)%rule.Thereafter == 0 {
+		return true, rule.Thereafter
+	}
+	return false, 0
+}
+
+// pruneExpired removes the counters whose interval has ended. Must be called with s.mu held.
+func (s *sampler) pruneExpired(now time.Time) {
+	for key, c := range s.counters {
+		if !now.Before(c.resetAt) {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampling(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	require.NoError(t, lg.SetSamplingRules([]SamplingRule{
+		{Title: "hot", First: 2, Thereafter: 3, Interval: time.Minute},
+	}))
+	now := time.Now()
+	lg.SetClock(func() time.Time { return now })
+
+	for i := 0; i < 8; i++ {
+		lg.InfoD("hot", M{"i": i})
+		lg.Info("cold")
+	}
+	lines := decodeLines(t, buf)
+
+	hot := []map[string]interface{}{}
+	for _, line := range lines {
+		if line["title"] == "hot" {
+			hot = append(hot, line)
+		}
+	}
+	assert.Len(t, lines, 8+4)
+	require.Len(t, hot, 4)
+	for i, expected := range []struct {
+		i          float64
+		sampleRate interface{}
+	}{{0, nil}, {1, nil}, {4, 3.0}, {7, 3.0}} {
+		assert.Equal(t, expected.i, hot[i]["i"])
+		assert.Equal(t, expected.sampleRate, hot[i]["sample_rate"])
+	}
+
+	t.Log("counts reset after the interval")
+	buf.Reset()
+	now = now.Add(time.Minute)
+	lg.Info("hot")
+	assert.Len(t, decodeLines(t, buf), 1)
+	assert.NotContains(t, buf.String(), "sample_rate")
+}
+
+func TestSamplingBurstLimitAndErrors(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	require.NoError(t, lg.SetSamplingRules([]SamplingRule{
+		{Title: "*", First: 1},
+	}))
+
+	lg.Info("a")
+	lg.Info("a")
+	lg.Info("b")
+	lg.Error("a")
+	lg.Critical("a")
+	assert.Len(t, decodeLines(t, buf), 4, "a, b and both error levels should be logged")
+
+	buf.Reset()
+	require.NoError(t, lg.SetSamplingRules([]SamplingRule{
+		{Title: "*", First: 1, SampleErrors: true},
+	}))
+	lg.Error("a")
+	lg.Error("a")
+	assert.Len(t, decodeLines(t, buf), 1)
+
+	buf.Reset()
+	require.NoError(t, lg.SetSamplingRules(nil))
+	lg.Error("a")
+	lg.Error("a")
+	assert.Len(t, decodeLines(t, buf), 2)
+}
+
+func TestSamplingKeyFields(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	require.NoError(t, lg.SetSamplingRules([]SamplingRule{
+		{Title: "query", KeyFields: []string{"table"}, First: 1},
+	}))
+
+	child := lg.With(M{"table": "schools"})
+	for i := 0; i < 3; i++ {
+		lg.InfoD("query", M{"table": "students"})
+		child.Info("query")
+	}
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "students", lines[0]["table"])
+	assert.Equal(t, "schools", lines[1]["table"])
+}
+
+func TestSetSamplingFromConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kayvee-sampling")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "kvconfig.yml")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`
+routes: {}
+sampling:
+  - title: query
+    key_fields: [table]
+    first: 100
+    thereafter: 10
+    interval: 5s
+`), 0644))
+
+	lg := NewConcreteLogger("logger-tester")
+	require.NoError(t, lg.SetSamplingFromConfig(filename))
+	assert.Equal(t, map[string]SamplingRule{
+		"query": {Title: "query", KeyFields: []string{"table"}, First: 100, Thereafter: 10, Interval: 5 * time.Second},
+	}, lg.settings.Load().sampler.rules)
+
+	assert.Error(t, lg.SetSamplingFromConfigBytes([]byte("sampling:\n  - first: 1\n")))
+	assert.Error(t, lg.SetSamplingFromConfigBytes([]byte("sampling:\n  - {title: a}\n  - {title: a}\n")))
+	assert.Error(t, lg.SetSamplingFromConfig(filepath.Join(dir, "missing.yml")))
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler is an slog.Handler that writes records through a *Logger, so lines produced via
+// the log/slog API are formatted and routed exactly like lines produced by the *D methods.
+// The record message becomes the kayvee title and attributes become data fields. Groups are
+// written as nested objects so they can be matched by dotted paths in routing rules.
+type SlogHandler struct {
+	logger *Logger
+	goas   []groupOrAttrs
+}
+
+// groupOrAttrs holds either a group name or a list of attrs, in the order they were added to
+// the handler via WithGroup and WithAttrs. The first entry is always a group, since attrs added
+// before any group are stored as logger globals instead.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+var _ slog.Handler = &SlogHandler{}
+
+// NewSlogHandler returns an slog.Handler that logs through `l`.
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{logger: l}
+}
+
+// NewSlogLogger returns an *slog.Logger that logs through `l`.
+func NewSlogLogger(l *Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(l))
+}
+
+// LogLevelFromSlog maps an slog.Level onto the closest LogLevel. Levels below slog.LevelDebug
+// map to Trace and levels at or above slog.LevelError+4 map to Critical.
+func LogLevelFromSlog(level slog.Level) LogLevel {
+	switch {
+	case level < slog.LevelDebug:
+		return Trace
+	case level < slog.LevelInfo:
+		return Debug
+	case level < slog.LevelWarn:
+		return Info
+	case level < slog.LevelError:
+		return Warning
+	case level < slog.LevelError+4:
+		return Error
+	default:
+		return Critical
+	}
+}
+
+// Enabled implements the slog.Handler interface. Since the title isn't known yet, it allows
+// every level that a level override could allow; Handle then filters by title and source.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return LogLevelFromSlog(level) >= h.logger.lowestLevel()
+}
+
+// Handle implements the slog.Handler interface. Like the *Ctx logging methods, it adds the
+// fields returned by the registered context extractors, e.g. the trace and span IDs of the
+// active span in ctx.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	data := M{}
+
+	// Attrs added with WithAttrs after WithGroup are written first so that attrs on the record
+	// itself take precedence.
+	var path []string
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			path = append(path, goa.group)
+			continue
+		}
+		for _, a := range goa.attrs {
+			addSlogAttr(data, path, a)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(data, path, a)
+		return true
+	})
+	addContextFields(ctx, data)
+
+	data["title"] = r.Message
+	// the record knows its caller, which is below log/slog's frames on the stack
+	h.logger.addCallerFromPC(data, r.PC)
+	h.logger.logWithLevel(LogLevelFromSlog(r.Level), data)
+	return nil
+}
+
+// WithAttrs implements the slog.Handler interface. Attrs added outside of any group become
+// globals of a child logger, as if added with Logger.With.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	if len(h.goas) == 0 {
+		fields := M{}
+		for _, a := range attrs {
+			addSlogAttr(fields, nil, a)
+		}
+		return &SlogHandler{logger: h.logger.with(fields)}
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{group: name})
+}
+
+func (h *SlogHandler) withGroupOrAttrs(goa groupOrAttrs) *SlogHandler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas[len(h2.goas)-1] = goa
+	return &h2
+}
+
+// addSlogAttr writes `a` into `data` under the nested objects named by `path`. Nested objects
+// are only created once there is a non-empty attr to put in them, matching the slog rule that
+// empty groups are omitted.
+func addSlogAttr(data M, path []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+		if a.Key != "" {
+			// Copy so that appending never touches the caller's path slice.
+			path = append(path[:len(path):len(path)], a.Key)
+		}
+		for _, ga := range attrs {
+			addSlogAttr(data, path, ga)
+		}
+		return
+	}
+
+	target := data
+	for _, group := range path {
+		sub, ok := target[group].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			target[group] = sub
+		}
+		target = sub
+	}
+	target[a.Key] = slogValue(a.Value)
+}
+
+// slogValue converts a resolved, non-group slog.Value into a value that kv.Format can
+// marshal.
+func slogValue(v slog.Value) interface{} {
+	switch v.Kind() {
+	case slog.KindAny:
+		// errors marshal to {} by default, so log their message instead
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		return v.Any()
+	default:
+		return v.Any()
+	}
+}
@@ -0,0 +1,167 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/Clever/kayvee-go/v7/router"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLogLevelFromSlog(t *testing.T) {
+	assert.Equal(t, Trace, LogLevelFromSlog(slog.LevelDebug-1))
+	assert.Equal(t, Debug, LogLevelFromSlog(slog.LevelDebug))
+	assert.Equal(t, Info, LogLevelFromSlog(slog.LevelInfo))
+	assert.Equal(t, Warning, LogLevelFromSlog(slog.LevelWarn))
+	assert.Equal(t, Error, LogLevelFromSlog(slog.LevelError))
+	assert.Equal(t, Critical, LogLevelFromSlog(slog.LevelError+4))
+}
+
+func TestSlogHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	slg := NewSlogLogger(lg)
+
+	slg.Info("testloginfo", "key1", "val1", "key2", 2)
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Info, "testloginfo", M{"key1": "val1", "key2": 2}))
+
+	buf.Reset()
+	slg.Error("testlogerror", "error", errors.New("oh no"))
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Error, "testlogerror", M{"error": "oh no"}))
+}
+
+func TestSlogHandlerGroupsAndAttrs(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	slg := NewSlogLogger(lg).With("a", "global", "b", "global").WithGroup("req")
+
+	slg.Warn("testlogwarning", "method", "GET", slog.Group("user", "id", "u1"), slog.Group("empty"))
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Warning, "testlogwarning", M{
+			"a": "global",
+			"b": "global",
+			"req": M{
+				"method": "GET",
+				"user":   M{"id": "u1"},
+			},
+		}))
+
+	buf.Reset()
+	NewSlogLogger(lg).With("a", "global").Info("testloginfo", "a", "record")
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Info, "testloginfo", M{"a": "record"}))
+
+	buf.Reset()
+	NewSlogLogger(lg).WithGroup("empty").Info("testloginfo")
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Info, "testloginfo", M{}))
+}
+
+func TestSlogHandlerWithAttrsAddsGlobals(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	h, ok := NewSlogHandler(lg).WithAttrs([]slog.Attr{
+		slog.String("a", "global"),
+		slog.Group("user", "id", "u1"),
+	}).(*SlogHandler)
+	require.True(t, ok)
+
+	val, ok := h.logger.GetContext("a")
+	assert.True(t, ok)
+	assert.Equal(t, "global", val)
+	val, ok = h.logger.GetContext("user")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"id": "u1"}, val)
+	_, ok = lg.GetContext("a")
+	assert.False(t, ok, "parent logger is unchanged")
+
+	h.logger.Info("testloginfo")
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog(
+		"logger-tester", kv.Info, "testloginfo", M{"a": "global", "user": M{"id": "u1"}}))
+}
+
+func TestSlogHandlerContextFields(t *testing.T) {
+	type requestKey struct{}
+	RegisterContextExtractor("test-request", func(ctx context.Context) M {
+		if id, ok := ctx.Value(requestKey{}).(string); ok {
+			return M{"request_id": id}
+		}
+		return nil
+	})
+	defer UnregisterContextExtractor("test-request")
+
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = context.WithValue(ctx, requestKey{}, "r1")
+
+	NewSlogLogger(lg).InfoContext(ctx, "testloginfo", "key1", "val1")
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog("logger-tester", kv.Info, "testloginfo", M{
+		"key1":       "val1",
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":    "00f067aa0ba902b7",
+		"request_id": "r1",
+	}))
+
+	t.Log("record attrs take precedence")
+	buf.Reset()
+	NewSlogLogger(lg).InfoContext(ctx, "testloginfo", "request_id", "from-record")
+	assertLogFormatAndCompareContent(t, buf.String(), kv.FormatLog("logger-tester", kv.Info, "testloginfo", M{
+		"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":    "00f067aa0ba902b7",
+		"request_id": "from-record",
+	}))
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	lg.SetLogLevel(Warning)
+	h := NewSlogHandler(lg)
+
+	assert.False(t, h.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelWarn))
+
+	NewSlogLogger(lg).Info("testloginfo")
+	assert.Equal(t, "", buf.String())
+}
+
+func TestSlogHandlerRouting(t *testing.T) {
+	testRouter, err := router.NewFromRoutes(map[string]router.Rule{
+		"rule-one": {
+			Matchers: router.RuleMatchers{"req.user.id": []string{"u1"}},
+			Output:   router.RuleOutput{"out": "%{req.method}"},
+		},
+	})
+	require.NoError(t, err)
+
+	mockLogger := NewMockCountLogger("logger-tester")
+	mockLogger.SetRouter(testRouter)
+	lg, ok := mockLogger.logger.(*Logger)
+	require.True(t, ok)
+
+	NewSlogLogger(lg).WithGroup("req").Info("testloginfo", "method", "GET", slog.Group("user", "id", "u1"))
+	assert.Equal(t, map[string][]router.RuleOutput{
+		"rule-one": {router.RuleOutput{"rule": "rule-one", "out": "GET"}},
+	}, mockLogger.RuleOutputs())
+}
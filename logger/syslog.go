@@ -0,0 +1,366 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyslogFacility is the facility of the messages sent by a SyslogWriter, see RFC 5424 section
+// 6.2.1.
+type SyslogFacility int
+
+// Constants used to define the SyslogFacilities supported
+const (
+	SyslogUser   SyslogFacility = 1
+	SyslogDaemon SyslogFacility = 3
+	SyslogLocal0 SyslogFacility = 16
+	SyslogLocal1 SyslogFacility = 17
+	SyslogLocal2 SyslogFacility = 18
+	SyslogLocal3 SyslogFacility = 19
+	SyslogLocal4 SyslogFacility = 20
+	SyslogLocal5 SyslogFacility = 21
+	SyslogLocal6 SyslogFacility = 22
+	SyslogLocal7 SyslogFacility = 23
+)
+
+// syslogSeverities maps LogLevels to syslog severities.
+var syslogSeverities = map[LogLevel]int{
+	Trace:    7, // debug
+	Debug:    7, // debug
+	Info:     6, // informational
+	Warning:  4, // warning
+	Error:    3, // error
+	Critical: 2, // critical
+}
+
+// syslogNoticeSeverity is the severity of lines that have no kayvee level.
+const syslogNoticeSeverity = 5
+
+// defaultSyslogSDID is the SD-ID of the structured data element holding the fields of a line.
+// 32473 is the private enterprise number reserved for documentation by RFC 5612.
+const defaultSyslogSDID = "kayvee@32473"
+
+const nilSyslogValue = "-"
+
+const (
+	minSyslogReconnectBackoff = 100 * time.Millisecond
+	maxSyslogReconnectBackoff = 30 * time.Second
+)
+
+// errSyslogDisconnected is returned for lines dropped while a SyslogWriter is reconnecting.
+var errSyslogDisconnected = errors.New("syslog output is disconnected, line dropped")
+
+// SyslogConfig configures a SyslogWriter. See NewSyslogWriter.
+type SyslogConfig struct {
+	// Network is "udp", "tcp", "unix" (stream) or "unixgram". Messages sent over streams are
+	// framed by octet counting (RFC 6587).
+	Network string
+	// Address is the host:port, or socket path, of the syslog server.
+	Address string
+	// Facility is the facility of the messages. Defaults to SyslogUser.
+	Facility SyslogFacility
+	// Hostname is the HOSTNAME of the messages. Defaults to os.Hostname.
+	Hostname string
+	// StructuredData sends the fields of each line as RFC 5424 STRUCTURED-DATA instead of
+	// sending the line as the message.
+	StructuredData bool
+	// SDID is the SD-ID of the structured data element. Defaults to "kayvee@32473".
+	SDID string
+	// DialTimeout is the timeout for connecting to the server. Defaults to 5 seconds.
+	DialTimeout time.Duration
+	// WriteTimeout is the timeout for sending each message. A server that stops reading makes
+	// writes time out, which drops the line and reconnects. Defaults to 5 seconds.
+	WriteTimeout time.Duration
+}
+
+// SyslogWriter is an io.Writer that sends kayvee lines to a syslog server as RFC 5424
+// messages, e.g. for infrastructure that only ingests syslog:
+//
+//	sw, err := logger.NewSyslogWriter(logger.SyslogConfig{Network: "tcp", Address: "syslog:601"})
+//	...
+//	defer sw.Close()
+//	lg.SetOutput(sw)
+//
+// The level of a line determines the severity of its message, its source the APP-NAME and its
+// title the MSGID. Lines that aren't JSON, e.g. from another formatter, are sent as is with
+// the notice severity. If the connection fails, or a write times out because the server
+// stopped reading, the writer reconnects in the background with backoff, and lines written
+// until it is reconnected are dropped rather than blocking the caller. SyslogWriter is safe for
+// concurrent use.
+type SyslogWriter struct {
+	config SyslogConfig
+	now    func() time.Time
+	pid    string
+
+	dial func(network, address string, timeout time.Duration) (net.Conn, error)
+
+	mu   sync.Mutex
+	conn net.Conn
+	// dropped is the number of lines dropped since the connection was lost.
+	dropped int
+	closed  bool
+	done    chan struct{}
+}
+
+// NewSyslogWriter connects to the syslog server at config.Address.
+func NewSyslogWriter(config SyslogConfig) (*SyslogWriter, error) {
+	switch config.Network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported syslog network '%s'", config.Network)
+	}
+	if config.Address == "" {
+		return nil, errors.New("syslog output has no address")
+	}
+	if config.Facility == 0 {
+		config.Facility = SyslogUser
+	}
+	if config.Hostname == "" {
+		config.Hostname, _ = os.Hostname()
+	}
+	if config.SDID == "" {
+		config.SDID = defaultSyslogSDID
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 5 * time.Second
+	}
+	w := &SyslogWriter{
+		config: config,
+		now:    time.Now,
+		pid:    strconv.Itoa(os.Getpid()),
+		dial:   net.DialTimeout,
+		done:   make(chan struct{}),
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements io.Writer, sending each line in p as a syslog message.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if err := w.send(w.message(line)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes the connection to the server and stops reconnecting.
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// connect dials the server. w.mu must be held, or w not yet shared.
+func (w *SyslogWriter) connect() error {
+	conn, err := w.dial(w.config.Network, w.config.Address, w.config.DialTimeout)
+	if err != nil {
+		return fmt.Errorf("can't connect to syslog server: %s", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// send writes msg to the server. If the writer is disconnected, msg is dropped so that logging
+// doesn't block on the server; if the write fails or times out, the writer starts reconnecting.
+func (w *SyslogWriter) send(msg []byte) error {
+	if w.config.Network == "tcp" || w.config.Network == "unix" {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.conn == nil {
+		w.dropped++
+		return errSyslogDisconnected
+	}
+	// w.now is only the clock of the message timestamps, so the deadline uses the real time
+	err := w.conn.SetWriteDeadline(time.Now().Add(w.config.WriteTimeout))
+	if err == nil {
+		_, err = w.conn.Write(msg)
+	}
+	if err != nil {
+		w.conn.Close()
+		w.conn = nil
+		w.dropped++
+		go w.reconnect()
+		return err
+	}
+	return nil
+}
+
+// reconnect dials the server until it succeeds or the writer is closed, backing off between
+// attempts.
+func (w *SyslogWriter) reconnect() {
+	backoff := minSyslogReconnectBackoff
+	for {
+		conn, err := w.dial(w.config.Network, w.config.Address, w.config.DialTimeout)
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		if err == nil {
+			w.conn = conn
+			dropped := w.dropped
+			w.dropped = 0
+			w.mu.Unlock()
+			log.Printf("WARN: kayvee syslog output dropped %d lines while disconnected", dropped)
+			return
+		}
+		w.mu.Unlock()
+
+		select {
+		case <-w.done:
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxSyslogReconnectBackoff {
+			backoff = maxSyslogReconnectBackoff
+		}
+	}
+}
+
+// message returns the RFC 5424 message for a kayvee line.
+func (w *SyslogWriter) message(line []byte) []byte {
+	data := decodeKayveeLine(line)
+	severity := syslogNoticeSeverity
+	if lvl, ok := data["level"].(string); ok {
+		if logLvl, err := ParseLogLevel(lvl); err == nil {
+			severity = syslogSeverities[logLvl]
+		}
+	}
+	source, _ := data["source"].(string)
+	title, _ := data["title"].(string)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %s %s ",
+		int(w.config.Facility)*8+severity,
+		w.now().UTC().Format("2006-01-02T15:04:05.000000Z"),
+		syslogHeaderValue(w.config.Hostname, 255),
+		syslogHeaderValue(source, 48),
+		w.pid,
+		syslogHeaderValue(title, 32),
+	)
+	if w.config.StructuredData && data != nil {
+		w.writeStructuredData(&buf, data)
+	} else {
+		buf.WriteString(nilSyslogValue)
+		buf.WriteByte(' ')
+		buf.Write(line)
+	}
+	return buf.Bytes()
+}
+
+// decodeKayveeLine returns the fields of a JSON log line, or nil if it isn't JSON. Numbers are
+// decoded as json.Number, so that integers stay integers.
+func decodeKayveeLine(line []byte) map[string]interface{} {
+	var data map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return nil
+	}
+	return data
+}
+
+// writeStructuredData writes the fields of a line as a structured data element, sorted by
+// name. Values that aren't strings are written as JSON.
+func (w *SyslogWriter) writeStructuredData(buf *bytes.Buffer, data map[string]interface{}) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf.WriteByte('[')
+	buf.WriteString(w.config.SDID)
+	for _, k := range keys {
+		name := syslogSDName(k)
+		if name == "" {
+			continue
+		}
+		value, ok := data[k].(string)
+		if !ok {
+			b, _ := json.Marshal(data[k])
+			value = string(b)
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(name)
+		buf.WriteString(`="`)
+		syslogSDEscaper.WriteString(buf, value)
+		buf.WriteByte('"')
+	}
+	buf.WriteByte(']')
+}
+
+// syslogSDEscaper escapes the characters that must be escaped in a PARAM-VALUE.
+var syslogSDEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+// syslogHeaderValue returns s as a header field of at most `max` printable ASCII characters,
+// or the NILVALUE if it is empty.
+func syslogHeaderValue(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return nilSyslogValue
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
+
+// syslogSDName returns s as a PARAM-NAME, which is at most 32 printable ASCII characters other
+// than '=', ' ', ']' and '"'.
+func syslogSDName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
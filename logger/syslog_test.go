@@ -0,0 +1,249 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readOctetCounted reads one octet-counted syslog message from r.
+func readOctetCounted(t *testing.T, r *bufio.Reader) string {
+	length, err := r.ReadString(' ')
+	require.NoError(t, err)
+	n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	require.NoError(t, err)
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	require.NoError(t, err)
+	return string(msg)
+}
+
+func newTestSyslogWriter(t *testing.T, config SyslogConfig) *SyslogWriter {
+	config.Hostname = "test-host"
+	sw, err := NewSyslogWriter(config)
+	require.NoError(t, err)
+	sw.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC) }
+	sw.pid = "123"
+	t.Cleanup(func() { sw.Close() })
+	return sw
+}
+
+func TestSyslogUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	sw := newTestSyslogWriter(t, SyslogConfig{Network: "udp", Address: pc.LocalAddr().String()})
+	lg := NewConcreteLogger("my-app")
+	lg.SetOutput(sw)
+	lg.WarnD("disk-full", M{"free": 0})
+
+	buf := make([]byte, 2048)
+	require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := pc.ReadFrom(buf)
+	require.NoError(t, err)
+	msg := string(buf[:n])
+
+	prefix := "<12>1 2024-01-02T03:04:05.000006Z test-host my-app 123 disk-full - "
+	require.True(t, strings.HasPrefix(msg, prefix), msg)
+	line := decodeLines(t, bytes.NewBufferString(strings.TrimPrefix(msg, prefix)))[0]
+	assert.Equal(t, "disk-full", line["title"])
+	assert.Equal(t, float64(0), line["free"])
+}
+
+func TestSyslogTCPStructuredData(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	sw := newTestSyslogWriter(t, SyslogConfig{
+		Network:        "tcp",
+		Address:        ln.Addr().String(),
+		Facility:       SyslogLocal0,
+		StructuredData: true,
+	})
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	_, err = sw.Write([]byte(`{"level":"error","source":"my-app","title":"failed","n":1,"err":"say \"hi\" [x]"}` + "\n" +
+		`{"level":"critical","source":"my-app","title":"down"}` + "\n"))
+	require.NoError(t, err)
+	_, err = sw.Write([]byte("not json\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, `<131>1 2024-01-02T03:04:05.000006Z test-host my-app 123 failed `+
+		`[kayvee@32473 err="say \"hi\" [x\]" level="error" n="1" source="my-app" title="failed"]`,
+		readOctetCounted(t, r))
+	assert.Equal(t, `<130>1 2024-01-02T03:04:05.000006Z test-host my-app 123 down `+
+		`[kayvee@32473 level="critical" source="my-app" title="down"]`,
+		readOctetCounted(t, r))
+	assert.Equal(t, `<133>1 2024-01-02T03:04:05.000006Z test-host - 123 - - not json`,
+		readOctetCounted(t, r))
+}
+
+func TestSyslogUnix(t *testing.T) {
+	addr := filepath.Join(t.TempDir(), "syslog.sock")
+	ln, err := net.Listen("unix", addr)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	sw := newTestSyslogWriter(t, SyslogConfig{Network: "unix", Address: addr})
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = sw.Write([]byte(`{"level":"debug","source":"my-app","title":"hello"}` + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, `<15>1 2024-01-02T03:04:05.000006Z test-host my-app 123 hello - `+
+		`{"level":"debug","source":"my-app","title":"hello"}`,
+		readOctetCounted(t, bufio.NewReader(conn)))
+}
+
+func TestSyslogReconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	conns := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	sw := newTestSyslogWriter(t, SyslogConfig{Network: "tcp", Address: ln.Addr().String()})
+	// the server drops the first connection
+	(<-conns).Close()
+
+	// writes on the dropped connection fail once the peer's reset arrives, and the writer then
+	// reconnects in the background
+	line := []byte(`{"level":"info","source":"my-app","title":"hello"}` + "\n")
+	var conn net.Conn
+	for deadline := time.Now().Add(5 * time.Second); conn == nil && time.Now().Before(deadline); {
+		sw.Write(line)
+		select {
+		case conn = <-conns:
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	require.NotNil(t, conn, "the writer didn't reconnect")
+	defer conn.Close()
+	assert.Eventually(t, func() bool {
+		_, err := sw.Write(line)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	assert.Contains(t, readOctetCounted(t, bufio.NewReader(conn)), " my-app 123 hello - ")
+}
+
+func TestSyslogConfigErrors(t *testing.T) {
+	_, err := NewSyslogWriter(SyslogConfig{Network: "http", Address: "localhost:514"})
+	assert.Error(t, err)
+	_, err = NewSyslogWriter(SyslogConfig{Network: "udp"})
+	assert.Error(t, err)
+	_, err = NewSyslogWriter(SyslogConfig{Network: "unix", Address: filepath.Join(os.TempDir(), "missing.sock")})
+	assert.Error(t, err)
+}
+
+func TestSyslogDropsLinesWhileServerIsDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	sw := newTestSyslogWriter(t, SyslogConfig{Network: "tcp", Address: ln.Addr().String()})
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+
+	// the server goes away, and reconnecting is slow, as it is for an unreachable host
+	var dials atomic.Int32
+	sw.mu.Lock()
+	sw.dial = func(network, address string, timeout time.Duration) (net.Conn, error) {
+		dials.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		return net.DialTimeout(network, address, timeout)
+	}
+	sw.mu.Unlock()
+	conn.Close()
+	ln.Close()
+
+	line := []byte(`{"level":"info","source":"my-app","title":"hello"}` + "\n")
+	start := time.Now()
+	var writeErr error
+	for i := 0; i < 100 && writeErr != errSyslogDisconnected; i++ {
+		_, writeErr = sw.Write(line)
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, errSyslogDisconnected, writeErr)
+	for i := 0; i < 100; i++ {
+		_, err := sw.Write(line)
+		assert.Equal(t, errSyslogDisconnected, err)
+	}
+	assert.Less(t, time.Since(start), 200*time.Millisecond, "writes don't wait for the dial")
+	assert.Equal(t, int32(1), dials.Load(), "one reconnection is attempted at a time")
+}
+
+func TestSyslogWriteTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	var mu sync.Mutex
+	var conns []net.Conn
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// accept, but never read
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	sw := newTestSyslogWriter(t, SyslogConfig{
+		Network:      "tcp",
+		Address:      ln.Addr().String(),
+		WriteTimeout: 50 * time.Millisecond,
+	})
+	line := []byte(`{"level":"info","source":"my-app","title":"` + strings.Repeat("x", 64*1024) + `"}` + "\n")
+	var writeErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// the socket buffers fill up, then a write times out
+		for i := 0; i < 10000 && writeErr == nil; i++ {
+			_, writeErr = sw.Write(line)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("writes blocked on a server that doesn't read")
+	}
+	var netErr net.Error
+	require.ErrorAs(t, writeErr, &netErr)
+	assert.True(t, netErr.Timeout())
+}
@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"errors"
+	"io"
+
+	kv "github.com/Clever/kayvee-go/v7"
+)
+
+// Sink is one of the outputs of a Logger that writes to several, see SetSinks.
+type Sink struct {
+	// Output is where the lines are written.
+	Output io.Writer
+	// Formatter formats the lines. Defaults to kv.Format.
+	Formatter Formatter
+	// MinLevel is the lowest level of the lines written. Lines below the logger's own level are
+	// never logged, so it only filters further.
+	MinLevel LogLevel
+	// Titles, if not empty, limits the lines written to those with one of these titles.
+	Titles []string
+}
+
+// SetSinks makes the logger (and its children) write every line to each of `sinks` that
+// accepts it, e.g. JSON lines on stderr for the log pipeline, readable lines at Debug in a
+// local file, and Error lines and above mirrored to another writer:
+//
+//	lg.SetSinks(
+//		logger.Sink{Output: os.Stderr, MinLevel: logger.Info},
+//		logger.Sink{Output: file, Formatter: logger.NewPrettyFormatter(false), MinLevel: logger.Debug},
+//		logger.Sink{Output: alerts, MinLevel: logger.Error},
+//	)
+//
+// A line is redacted, sampled and routed once, and the same line, including its `_kvmeta`, is
+// formatted for each sink. SetFormatter, SetOutput and SetConfig go back to a single output,
+// replacing the sinks. With SetAsync, lines are formatted for every sink on the caller's
+// goroutine and written in the background.
+func (l *Logger) SetSinks(sinks ...Sink) error {
+	if len(sinks) == 0 {
+		return errors.New("at least one sink is required")
+	}
+	tfl := &teeFormatLogger{sinks: make([]teeSink, len(sinks))}
+	for i, sink := range sinks {
+		if sink.Output == nil {
+			return errors.New("sink has no output")
+		}
+		formatter := sink.Formatter
+		if formatter == nil {
+			formatter = kv.Format
+		}
+		fl := (&defaultFormatLogger{}).withFormatter(formatter).withOutput(sink.Output)
+		tfl.sinks[i] = teeSink{fl: fl.(*defaultFormatLogger), minLevel: sink.MinLevel}
+		if len(sink.Titles) > 0 {
+			tfl.sinks[i].titles = make(map[string]bool, len(sink.Titles))
+			for _, title := range sink.Titles {
+				tfl.sinks[i].titles[title] = true
+			}
+		}
+	}
+
+	l.settingsL.Lock()
+	defer l.settingsL.Unlock()
+	s := *l.settings.Load()
+	current := s.fLogger
+	afl, async := current.(*asyncFormatLogger)
+	if async {
+		current = afl.fl
+	}
+	switch f := current.(type) {
+	case *defaultFormatLogger:
+		tfl.base = f
+	case *teeFormatLogger:
+		tfl.base = f.base
+	default:
+		return errors.New("sinks are not supported by this logger")
+	}
+	if async {
+		s.fLogger = &asyncFormatLogger{fl: tfl, q: afl.q}
+	} else {
+		s.fLogger = tfl
+	}
+	l.settings.Store(&s)
+	return nil
+}
+
+// teeFormatLogger implements the formatLogger interface by writing each line to the sinks that
+// accept it.
+type teeFormatLogger struct {
+	sinks []teeSink
+	// base is the single output the logger had before SetSinks, which it goes back to if its
+	// formatter or output is set.
+	base *defaultFormatLogger
+}
+
+type teeSink struct {
+	fl       *defaultFormatLogger
+	minLevel LogLevel
+	titles   map[string]bool
+}
+
+// formatAndLog implements the formatLogger interface for *teeFormatLogger.
+func (tfl *teeFormatLogger) formatAndLog(data map[string]interface{}) {
+	tfl.forSinks(data, func(fl *defaultFormatLogger) {
+		fl.formatAndLog(data)
+	})
+}
+
+// formatDeferred implements the deferredFormatLogger interface for *teeFormatLogger.
+func (tfl *teeFormatLogger) formatDeferred(data map[string]interface{}) func() {
+	writes := []func(){}
+	tfl.forSinks(data, func(fl *defaultFormatLogger) {
+		writes = append(writes, fl.formatDeferred(data))
+	})
+	return func() {
+		for _, write := range writes {
+			write()
+		}
+	}
+}
+
+// forSinks calls f with the formatLogger of each sink that accepts the line in data.
+func (tfl *teeFormatLogger) forSinks(data map[string]interface{}, f func(fl *defaultFormatLogger)) {
+	logLvl := logLevelFromData(data)
+	title, _ := data["title"].(string)
+	for _, sink := range tfl.sinks {
+		if logLvl < sink.minLevel || sink.titles != nil && !sink.titles[title] {
+			continue
+		}
+		f(sink.fl)
+	}
+}
+
+// withFormatter implements the formatLogger interface for *teeFormatLogger.
+func (tfl *teeFormatLogger) withFormatter(formatter Formatter) formatLogger {
+	return tfl.base.withFormatter(formatter)
+}
+
+// withOutput implements the formatLogger interface for *teeFormatLogger.
+func (tfl *teeFormatLogger) withOutput(output io.Writer) formatLogger {
+	return tfl.base.withOutput(output)
+}
@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	kv "github.com/Clever/kayvee-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRouter routes every line to the same output, counting the lines routed.
+type countingRouter struct {
+	calls atomic.Int32
+}
+
+func (r *countingRouter) Route(msg map[string]interface{}) map[string]interface{} {
+	r.calls.Add(1)
+	return map[string]interface{}{"routes": []map[string]interface{}{{"rule": "counted"}}}
+}
+
+func TestSinks(t *testing.T) {
+	pipeline, local, alerts := &bytes.Buffer{}, &bytes.Buffer{}, &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	r := &countingRouter{}
+	lg.SetRouter(r)
+	require.NoError(t, lg.SetSinks(
+		Sink{Output: pipeline, MinLevel: Info},
+		Sink{Output: local, Formatter: kv.FormatLogfmt, MinLevel: Debug},
+		Sink{Output: alerts, MinLevel: Error},
+	))
+
+	lg.TraceD("trace", M{"k": "v"})
+	lg.DebugD("debug", M{"k": "v"})
+	lg.Info("info", String("k", "v"))
+	lg.ErrorD("error", M{"k": "v"})
+	assert.Equal(t, int32(4), r.calls.Load(), "each line is routed once, not once per sink")
+
+	assert.Equal(t, []string{"info", "error"}, loggedTitles(t, pipeline))
+	assert.Equal(t, []string{"error"}, loggedTitles(t, alerts))
+	for _, line := range decodeLines(t, pipeline) {
+		assert.Equal(t, map[string]interface{}{
+			"routes": []interface{}{map[string]interface{}{"rule": "counted"}},
+		}, line["_kvmeta"])
+	}
+
+	localLines := strings.Split(strings.TrimSpace(local.String()), "\n")
+	require.Len(t, localLines, 3)
+	for i, title := range []string{"debug", "info", "error"} {
+		line, err := kv.ParseLogfmt(localLines[i])
+		require.NoError(t, err)
+		assert.Equal(t, title, line["title"])
+		assert.Equal(t, "v", line["k"])
+		assert.Contains(t, line, "_kvmeta")
+	}
+}
+
+func TestSinksTitles(t *testing.T) {
+	all, audit := &bytes.Buffer{}, &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	require.NoError(t, lg.SetSinks(
+		Sink{Output: all},
+		Sink{Output: audit, Titles: []string{"login", "logout"}},
+	))
+
+	lg.Info("login")
+	lg.Info("request")
+	lg.WarnD("logout", M{})
+	assert.Equal(t, []string{"login", "request", "logout"}, loggedTitles(t, all))
+	assert.Equal(t, []string{"login", "logout"}, loggedTitles(t, audit))
+}
+
+func TestSinksChildLoggers(t *testing.T) {
+	first, second := &bytes.Buffer{}, &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	child := lg.With(M{"request": "r1"})
+	require.NoError(t, lg.SetSinks(Sink{Output: first}, Sink{Output: second}))
+
+	child.Info("child")
+	assert.Equal(t, []string{"child"}, loggedTitles(t, first))
+	assert.Equal(t, []string{"child"}, loggedTitles(t, second))
+}
+
+func TestSinksReplacedBySetOutput(t *testing.T) {
+	sink, out := &bytes.Buffer{}, &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	require.NoError(t, lg.SetSinks(Sink{Output: sink}))
+	require.NoError(t, lg.SetSinks(Sink{Output: sink, Titles: []string{"only"}}))
+	lg.Info("first")
+	lg.Info("only")
+
+	lg.SetOutput(out)
+	lg.Info("second")
+	assert.Equal(t, []string{"only"}, loggedTitles(t, sink))
+	assert.Equal(t, []string{"second"}, loggedTitles(t, out))
+
+	buf := &bytes.Buffer{}
+	out.Reset()
+	require.NoError(t, lg.SetSinks(Sink{Output: buf}))
+	lg.SetFormatter(kv.Format)
+	lg.Info("third")
+	assert.Equal(t, []string{"third"}, loggedTitles(t, out), "SetFormatter keeps the output from before SetSinks")
+	assert.Empty(t, buf.String())
+}
+
+func TestSinksErrors(t *testing.T) {
+	lg := NewConcreteLogger("logger-tester")
+	assert.Error(t, lg.SetSinks())
+	assert.Error(t, lg.SetSinks(Sink{Output: &bytes.Buffer{}}, Sink{}))
+
+	mockLogger := NewMockCountLogger("testing")
+	assert.Error(t, mockLogger.logger.(*Logger).SetSinks(Sink{Output: &bytes.Buffer{}}))
+}
+
+func TestSinksAsync(t *testing.T) {
+	for _, sinksFirst := range []bool{true, false} {
+		lg := NewConcreteLogger("logger-tester")
+		all, errs := newGatedWriter(), newGatedWriter()
+		all.release()
+		errs.release()
+		sinks := []Sink{{Output: all}, {Output: errs, MinLevel: Error}}
+		if sinksFirst {
+			require.NoError(t, lg.SetSinks(sinks...))
+			require.NoError(t, lg.SetAsync(AsyncConfig{}))
+		} else {
+			require.NoError(t, lg.SetAsync(AsyncConfig{}))
+			require.NoError(t, lg.SetSinks(sinks...))
+		}
+		lg.Info("info")
+		lg.Error("error")
+		require.NoError(t, lg.Close())
+		assert.Equal(t, []string{"info", "error"}, all.lines(t))
+		assert.Equal(t, []string{"error"}, errs.lines(t))
+	}
+}
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"strings"
+	"time"
+)
+
+// TimestampFormat is an enum used to denote how the time of each log call is recorded.
+type TimestampFormat int32
+
+// Constants used to define different TimestampFormats supported
+const (
+	// NoTimestamp adds no timestamp. This is the default.
+	NoTimestamp TimestampFormat = iota
+	// TimestampRFC3339Nano adds the time in UTC as an RFC 3339 string with nanoseconds, e.g.
+	// "2026-10-16T04:45:06.123456789Z".
+	TimestampRFC3339Nano
+	// TimestampEpochMillis adds the time as milliseconds since the Unix epoch.
+	TimestampEpochMillis
+)
+
+// TimestampKey is the field the timestamp is added under.
+const TimestampKey = "timestamp"
+
+var timestampFormatNames = map[TimestampFormat]string{
+	NoTimestamp:          "none",
+	TimestampRFC3339Nano: "rfc3339nano",
+	TimestampEpochMillis: "epoch_ms",
+}
+
+func (f TimestampFormat) String() string {
+	return timestampFormatNames[f]
+}
+
+// timestampFormatFromEnv returns the TimestampFormat named by KAYVEE_TIMESTAMP_FORMAT.
+func timestampFormatFromEnv(name string) TimestampFormat {
+	for key, val := range timestampFormatNames {
+		if strings.ToLower(name) == val {
+			return key
+		}
+	}
+	return NoTimestamp
+}
+
+// SetTimestampFormat sets how the logger and its children record the time of each log call.
+// The time is read when the log method is called, so it is the time of the event even for
+// outputs that write lines later, like asynchronous or batched ones. The default can also be
+// set with the KAYVEE_TIMESTAMP_FORMAT environment variable: "rfc3339nano", "epoch_ms" or
+// "none".
+func (l *Logger) SetTimestampFormat(format TimestampFormat) {
+	l.timestampFormat.Store(int32(format))
+}
+
+// TimestampFormat returns how the logger records the time of each log call.
+func (l *Logger) TimestampFormat() TimestampFormat {
+	return TimestampFormat(l.timestampFormat.Load())
+}
+
+// addTimestamp adds the current time to data, unless data already has a timestamp.
+func (l *Logger) addTimestamp(data map[string]interface{}) {
+	format := l.TimestampFormat()
+	if format == NoTimestamp {
+		return
+	}
+	if _, ok := data[TimestampKey]; ok {
+		return
+	}
+	now := l.now()
+	switch format {
+	case TimestampRFC3339Nano:
+		data[TimestampKey] = now.UTC().Format(time.RFC3339Nano)
+	case TimestampEpochMillis:
+		data[TimestampKey] = now.UnixMilli()
+	}
+}
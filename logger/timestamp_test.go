@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestamps(t *testing.T) {
+	buf := &bytes.Buffer{}
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(buf)
+	now := time.Date(2026, 10, 16, 4, 45, 6, 123456789, time.FixedZone("PDT", -7*60*60))
+	lg.SetClock(func() time.Time { return now })
+
+	lg.Info("none")
+	lg.SetTimestampFormat(TimestampRFC3339Nano)
+	lg.With(M{"a": "b"}).Info("rfc3339nano")
+	lg.SetTimestampFormat(TimestampEpochMillis)
+	lg.Info("epoch_ms")
+	lg.InfoD("override", M{TimestampKey: "yesterday"})
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 4)
+	assert.NotContains(t, lines[0], TimestampKey)
+	assert.Equal(t, "2026-10-16T11:45:06.123456789Z", lines[1][TimestampKey])
+	assert.Equal(t, float64(now.UnixMilli()), lines[2][TimestampKey])
+	assert.Equal(t, "yesterday", lines[3][TimestampKey])
+}
+
+func TestTimestampCapturedAtCallTime(t *testing.T) {
+	w := newGatedWriter()
+	lg := NewConcreteLogger("logger-tester")
+	lg.SetOutput(w)
+	require.NoError(t, lg.SetAsync(AsyncConfig{}))
+	lg.SetTimestampFormat(TimestampEpochMillis)
+	now := time.UnixMilli(1000)
+	lg.SetClock(func() time.Time { return now })
+
+	lg.Info("line0")
+	<-w.entered
+	lg.Info("line1")
+	now = time.UnixMilli(5000)
+	w.release()
+	require.NoError(t, lg.Close())
+
+	lines := decodeLines(t, &w.buf)
+	require.Len(t, lines, 2)
+	assert.Equal(t, 1000.0, lines[0][TimestampKey])
+	assert.Equal(t, 1000.0, lines[1][TimestampKey])
+}
+
+func TestTimestampFormatFromEnv(t *testing.T) {
+	t.Setenv("KAYVEE_TIMESTAMP_FORMAT", "RFC3339Nano")
+	assert.Equal(t, TimestampRFC3339Nano, NewConcreteLogger("logger-tester").TimestampFormat())
+	t.Setenv("KAYVEE_TIMESTAMP_FORMAT", "epoch_ms")
+	assert.Equal(t, TimestampEpochMillis, NewConcreteLogger("logger-tester").TimestampFormat())
+	t.Setenv("KAYVEE_TIMESTAMP_FORMAT", "")
+	assert.Equal(t, NoTimestamp, NewConcreteLogger("logger-tester").TimestampFormat())
+	assert.Equal(t, "epoch_ms", TimestampEpochMillis.String())
+}
@@ -9,7 +9,13 @@ Package middleware provides a customizable Kayvee logging middleware for HTTP se
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/Clever/kayvee-go/v7/logger"
@@ -31,25 +37,84 @@ var defaultHandler = func(req *http.Request) map[string]interface{} {
 	return data
 }
 
+// RequestIDHeader is the request header the middleware reads the request ID from. If it is not
+// set, or isn't a valid request ID of at most 128 letters, digits, '-', '_', '.' and ':', a
+// random request ID is generated.
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKeyType struct{}
+
+var requestIDKey = requestIDKeyType{}
+
+// maxRequestIDLength is the longest request ID accepted from RequestIDHeader.
+const maxRequestIDLength = 128
+
+var registerExtractorOnce sync.Once
+
+// registerRequestIDExtractor attaches the request ID to lines logged with the logger's *Ctx
+// methods. It is registered by the first middleware created, rather than on import.
+func registerRequestIDExtractor() {
+	registerExtractorOnce.Do(func() {
+		logger.RegisterContextExtractor("request_id", func(ctx context.Context) logger.M {
+			if id, ok := RequestIDFromContext(ctx); ok {
+				return logger.M{"request_id": id}
+			}
+			return nil
+		})
+	})
+}
+
+// RequestIDFromContext returns the request ID the middleware placed in a request's context.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
+
+// PanicPolicy says what the middleware does when the handler it wraps panics.
+type PanicPolicy int
+
+const (
+	// PanicPropagate leaves panics alone. This is the default.
+	PanicPropagate PanicPolicy = iota
+	// PanicLogAndRepanic logs the panic with its stack at Critical, flushes the request logger,
+	// and panics again with the same value.
+	PanicLogAndRepanic
+	// PanicLogAndRespond500 logs the panic like PanicLogAndRepanic, then responds with a 500 if
+	// the handler has not written a response yet, and logs the request as finished.
+	PanicLogAndRespond500
+)
+
+// RequestPanicTitle is the title the middleware logs panics in the handlers it wraps with.
+const RequestPanicTitle = "request-panic"
+
+// Options configures the middleware created by NewWithOptions.
+type Options struct {
+	// PanicPolicy says what to do when the wrapped handler panics.
+	PanicPolicy PanicPolicy
+}
+
 type logHandler struct {
-	handlers []func(req *http.Request) map[string]interface{}
-	h        http.Handler
-	source   string
+	handlers    []func(req *http.Request) map[string]interface{}
+	h           http.Handler
+	source      string
+	panicPolicy PanicPolicy
 }
 
 func (l *logHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	start := time.Now()
 
-	// create and inject a logger into req.Context
+	// create and inject a logger and request ID into req.Context
 	lggr := logger.New(l.source)
-	req = req.WithContext(logger.NewContext(req.Context(), lggr))
+	ctx := logger.NewContext(req.Context(), lggr)
+	ctx = context.WithValue(ctx, requestIDKey, getRequestID(req))
+	req = req.WithContext(ctx)
 
 	lrw := &loggedResponseWriter{
 		status:         200,
 		ResponseWriter: w,
 		length:         0,
 	}
-	l.h.ServeHTTP(lrw, req)
+	l.serve(lrw, req, lggr)
 	duration := time.Since(start)
 
 	data := l.applyHandlers(req, map[string]interface{}{
@@ -69,14 +134,38 @@ func (l *logHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 
 	switch logLevelFromStatus(lrw.status) {
 	case logger.Error:
-		lggr.ErrorD("request-finished", data)
+		lggr.ErrorCtx(req.Context(), "request-finished", data)
 	case logger.Warning:
-		lggr.WarnD("request-finished", data)
+		lggr.WarnCtx(req.Context(), "request-finished", data)
 	default:
-		lggr.InfoD("request-finished", data)
+		lggr.InfoCtx(req.Context(), "request-finished", data)
 	}
 }
 
+// serve calls the wrapped handler, handling panics according to the panic policy.
+func (l *logHandler) serve(lrw *loggedResponseWriter, req *http.Request, lggr logger.KayveeLogger) {
+	if l.panicPolicy != PanicPropagate {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				// net/http uses this panic to abort a response silently
+				panic(r)
+			}
+			logger.LogPanic(lggr, RequestPanicTitle, r, debug.Stack())
+			if l.panicPolicy == PanicLogAndRepanic {
+				panic(r)
+			}
+			if !lrw.wroteHeader {
+				http.Error(lrw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+	}
+	l.h.ServeHTTP(lrw, req)
+}
+
 func (l *logHandler) applyHandlers(req *http.Request, finalizer map[string]interface{}) map[string]interface{} {
 	result := map[string]interface{}{}
 	writeData := func(data map[string]interface{}) {
@@ -97,12 +186,23 @@ func (l *logHandler) applyHandlers(req *http.Request, finalizer map[string]inter
 
 // New takes in an http Handler to wrap with logging, the logger source name to use, and any amount of
 // optional handlers to customize the data that's logged.
-// On every request, the middleware will create a logger and place it in req.Context().
+// On every request, the middleware will create a logger and place it in req.Context(). Its level
+// can be controlled with logger.SetGlobalLevelController.
 func New(h http.Handler, source string, handlers ...func(*http.Request) map[string]interface{}) http.Handler {
+	return NewWithOptions(h, source, Options{}, handlers...)
+}
+
+// NewWithOptions is like New, with Options to configure the middleware, e.g. to recover panics
+// in the wrapped handler.
+func NewWithOptions(
+	h http.Handler, source string, opts Options, handlers ...func(*http.Request) map[string]interface{},
+) http.Handler {
+	registerRequestIDExtractor()
 	return &logHandler{
-		handlers: handlers,
-		h:        h,
-		source:   source,
+		handlers:    handlers,
+		h:           h,
+		source:      source,
+		panicPolicy: opts.PanicPolicy,
 	}
 }
 
@@ -122,15 +222,18 @@ func HeaderHandler(headers ...string) func(*http.Request) map[string]interface{}
 type loggedResponseWriter struct {
 	status int
 	http.ResponseWriter
-	length int
+	length      int
+	wroteHeader bool
 }
 
 func (w *loggedResponseWriter) WriteHeader(code int) {
 	w.status = code
+	w.wroteHeader = true
 	w.ResponseWriter.WriteHeader(code)
 }
 
 func (w *loggedResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
 	n, err := w.ResponseWriter.Write(b)
 	w.length += n
 	return n, err
@@ -144,6 +247,35 @@ func getIP(req *http.Request) string {
 	return req.RemoteAddr
 }
 
+func getRequestID(req *http.Request) string {
+	if id := req.Header.Get(RequestIDHeader); validRequestID(id) {
+		return id
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// fall back to an ID that is unique enough to correlate the request's lines
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID returns whether id can be used as a request ID. IDs from clients are logged
+// verbatim, so they are limited in length and to characters that are safe in any log format.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func logLevelFromStatus(status int) logger.LogLevel {
 	if status >= 499 {
 		return logger.Error
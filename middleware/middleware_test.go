@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
@@ -110,6 +111,9 @@ func TestMiddleware(t *testing.T) {
 
 		delete(result, "response-time")
 		delete(result, "response-time-ms")
+		// the request ID is random, and attached by the request-finished line's context
+		assert.Len(result["request_id"], 16)
+		delete(result, "request_id")
 
 		test.expectedLog["ip"] = "192.168.0.1"
 		test.expectedLog["path"] = "path"
@@ -157,3 +161,106 @@ func TestMiddlewareIsAddedToContext(t *testing.T) {
 		t.Fatalf("invalid log title %s", result["title"])
 	}
 }
+
+func TestMiddlewareAddsRequestIDToContext(t *testing.T) {
+	assert := assert.New(t)
+
+	invalid := []http.Header{
+		{RequestIDHeader: {"abc def"}},
+		{RequestIDHeader: {"abc\n{\"level\":\"critical\"}"}},
+		{RequestIDHeader: {strings.Repeat("a", maxRequestIDLength+1)}},
+	}
+	for _, header := range append([]http.Header{{RequestIDHeader: {"abc-123_x.y:z"}}, {}}, invalid...) {
+		out := &bytes.Buffer{}
+		var requestID string
+		handler := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID, _ = RequestIDFromContext(r.Context())
+			lg := logger.FromContext(r.Context())
+			lg.SetConfig("my-source", logger.Info, kv.Format, out)
+			lg.InfoCtx(r.Context(), "logging with request id", nil)
+		}), "my-source")
+
+		handler.ServeHTTP(&bufferWriter{}, &http.Request{
+			Method: "GET",
+			URL:    &url.URL{Path: "path"},
+			Header: header,
+		})
+
+		if id := header.Get(RequestIDHeader); validRequestID(id) {
+			assert.Equal(id, requestID)
+		} else {
+			assert.Len(requestID, 16, "invalid request IDs are replaced")
+		}
+		var result map[string]interface{}
+		assert.Nil(json.NewDecoder(out).Decode(&result))
+		assert.Equal(requestID, result["request_id"])
+	}
+}
+
+func TestMiddlewarePanicRecovery(t *testing.T) {
+	assert := assert.New(t)
+
+	newHandler := func(out *bytes.Buffer, policy PanicPolicy, writeFirst bool) http.Handler {
+		return NewWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger.FromContext(r.Context()).SetConfig("my-source", logger.Info, kv.Format, out)
+			if writeFirst {
+				w.WriteHeader(202)
+			}
+			panic("boom")
+		}), "my-source", Options{PanicPolicy: policy})
+	}
+	req := &http.Request{Method: "GET", URL: &url.URL{Path: "path"}}
+	decode := func(out *bytes.Buffer) []map[string]interface{} {
+		lines := []map[string]interface{}{}
+		dec := json.NewDecoder(out)
+		for dec.More() {
+			var line map[string]interface{}
+			assert.Nil(dec.Decode(&line))
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	t.Log("panics propagate by default")
+	out := &bytes.Buffer{}
+	assert.PanicsWithValue("boom", func() {
+		newHandler(out, PanicPropagate, false).ServeHTTP(httptest.NewRecorder(), req)
+	})
+	assert.Empty(out.String())
+
+	t.Log("PanicLogAndRepanic logs the panic before re-panicking")
+	out = &bytes.Buffer{}
+	assert.PanicsWithValue("boom", func() {
+		newHandler(out, PanicLogAndRepanic, false).ServeHTTP(httptest.NewRecorder(), req)
+	})
+	lines := decode(out)
+	if assert.Len(lines, 1) {
+		assert.Equal(RequestPanicTitle, lines[0]["title"])
+		assert.Equal("critical", lines[0]["level"])
+		assert.Equal("boom", lines[0]["panic"])
+		assert.Contains(lines[0]["stack"], "middleware.TestMiddlewarePanicRecovery")
+	}
+
+	t.Log("PanicLogAndRespond500 responds with a 500 and logs the request")
+	out = &bytes.Buffer{}
+	rec := httptest.NewRecorder()
+	newHandler(out, PanicLogAndRespond500, false).ServeHTTP(rec, req)
+	assert.Equal(500, rec.Code)
+	lines = decode(out)
+	if assert.Len(lines, 2) {
+		assert.Equal(RequestPanicTitle, lines[0]["title"])
+		assert.Equal("request-finished", lines[1]["title"])
+		assert.Equal(500.0, lines[1]["status-code"])
+		assert.Equal("error", lines[1]["level"])
+	}
+
+	t.Log("the status isn't changed if the handler already wrote one")
+	out = &bytes.Buffer{}
+	rec = httptest.NewRecorder()
+	newHandler(out, PanicLogAndRespond500, true).ServeHTTP(rec, req)
+	assert.Equal(202, rec.Code)
+	lines = decode(out)
+	if assert.Len(lines, 2) {
+		assert.Equal(202.0, lines[1]["status-code"])
+	}
+}
@@ -0,0 +1,386 @@
+/*
+Package redact removes sensitive values, like emails or auth tokens, from log lines.
+
+A Policy is a list of Rules. Each rule matches fields by key name, by dotted path, or by a
+regular expression on string values, and replaces, hashes or drops what it matches:
+
+	policy, err := redact.New([]redact.Rule{
+		{Keys: []string{"password", "authorization"}, Action: redact.Drop},
+		{Paths: []string{"student.email"}, Action: redact.Hash},
+		{Values: []string{"email", "bearer_token", "ssn"}},
+	})
+
+Rules apply inside nested maps and slices, and inside structs and pointers, which are redacted as
+the JSON objects they are logged as. A struct with a redacted field is replaced by the
+equivalent map[string]interface{}.
+*/
+package redact
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Action is what a Rule does to the values it matches.
+type Action string
+
+// Constants used to define different Actions supported
+const (
+	// Replace replaces the value, or the part of a string matched by a value regex, with the
+	// rule's Replacement.
+	Replace Action = "replace"
+	// Hash replaces the value, or the part of a string matched by a value regex, with a hash of
+	// it, so that lines about the same value can still be correlated.
+	Hash Action = "hash"
+	// Drop removes the field.
+	Drop Action = "drop"
+)
+
+// DefaultReplacement is what Replace substitutes if a Rule has no Replacement.
+const DefaultReplacement = "[REDACTED]"
+
+// hashPrefix starts every value produced by Hash.
+const hashPrefix = "sha256:"
+
+// BuiltinPatterns are regular expressions for common sensitive values, which can be used by
+// name in Rule.Values.
+var BuiltinPatterns = map[string]string{
+	"email":        `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+	"bearer_token": `(?i)bearer\s+[A-Za-z0-9\-._~+/]+=*`,
+	"ssn":          `\b\d{3}-\d{2}-\d{4}\b`,
+}
+
+var hashedValue = regexp.MustCompile(`^` + hashPrefix + `[0-9a-f]{16}$`)
+
+// Rule says which fields to redact and how.
+type Rule struct {
+	// Keys are field names, matched case-insensitively at any depth.
+	Keys []string `yaml:"keys"`
+	// Paths are dotted paths to nested fields from the root of the line, e.g. "user.email",
+	// matched case-insensitively.
+	Paths []string `yaml:"paths"`
+	// Values are regular expressions matched against string values at any depth, or the names
+	// of BuiltinPatterns. Errors and fmt.Stringers are matched against their text, and replaced
+	// by the redacted text if it matches.
+	Values []string `yaml:"values"`
+	// Action is what to do with a match. Defaults to Replace.
+	Action Action `yaml:"action"`
+	// Replacement is the value Replace substitutes. Defaults to DefaultReplacement.
+	Replacement string `yaml:"replacement"`
+	// HashKey, if set, makes Hash use an HMAC with this key, so that hashes of guessable values
+	// like emails cannot be reversed by hashing candidates.
+	HashKey string `yaml:"hash_key"`
+}
+
+// Policy redacts log lines according to a list of Rules. A nil *Policy redacts nothing.
+// Applying a policy to a line it already redacted does not change it further.
+type Policy struct {
+	keys   map[string]*Rule
+	paths  map[string]*Rule
+	values []valueRule
+}
+
+type valueRule struct {
+	re   *regexp.Regexp
+	rule *Rule
+}
+
+// New compiles `rules` into a Policy. If several rules match a field, a path rule is preferred
+// over a key rule, and a key rule over value rules.
+func New(rules []Rule) (*Policy, error) {
+	p := &Policy{
+		keys:  map[string]*Rule{},
+		paths: map[string]*Rule{},
+	}
+	for i := range rules {
+		rule := rules[i]
+		switch rule.Action {
+		case "":
+			rule.Action = Replace
+		case Replace, Hash, Drop:
+		default:
+			return nil, fmt.Errorf("unknown redaction action '%s'", rule.Action)
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = DefaultReplacement
+		}
+		if len(rule.Keys) == 0 && len(rule.Paths) == 0 && len(rule.Values) == 0 {
+			return nil, fmt.Errorf("redaction rule %d has no keys, paths or values", i)
+		}
+		for _, key := range rule.Keys {
+			p.keys[strings.ToLower(key)] = &rule
+		}
+		for _, path := range rule.Paths {
+			p.paths[strings.ToLower(path)] = &rule
+		}
+		for _, value := range rule.Values {
+			pattern, ok := BuiltinPatterns[value]
+			if !ok {
+				pattern = value
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid redaction value regex '%s': %s", value, err)
+			}
+			p.values = append(p.values, valueRule{re: re, rule: &rule})
+		}
+	}
+	return p, nil
+}
+
+// FromConfig creates a Policy from the `redaction` section of the yaml file `filename`, e.g.
+//
+//	redaction:
+//	  - keys: [password, authorization]
+//	    action: drop
+//	  - paths: [student.email]
+//	    action: hash
+//	  - values: [email, bearer_token, ssn]
+//
+// It returns a nil Policy if the file has no redaction section.
+func FromConfig(filename string) (*Policy, error) {
+	fileBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p, err := FromConfigBytes(fileBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Error initializing kayvee redaction from file '%s':\n%s", filename, err.Error())
+	}
+	return p, nil
+}
+
+// FromConfigBytes is like FromConfig, with the yaml configuration passed as bytes.
+func FromConfigBytes(fileBytes []byte) (*Policy, error) {
+	var config struct {
+		Redaction []Rule `yaml:"redaction"`
+	}
+	if err := yaml.Unmarshal(fileBytes, &config); err != nil {
+		return nil, err
+	}
+	if len(config.Redaction) == 0 {
+		return nil, nil
+	}
+	return New(config.Redaction)
+}
+
+// Apply returns data with the policy applied. data itself, and any maps or slices nested in
+// it, are never modified: changed maps and slices are copied.
+func (p *Policy) Apply(data map[string]interface{}) map[string]interface{} {
+	if p == nil {
+		return data
+	}
+	out, _ := p.redactMap(data, "")
+	return out
+}
+
+// redactMap redacts the fields of m, whose dotted path is `path`. It returns m itself if
+// nothing changed.
+func (p *Policy) redactMap(m map[string]interface{}, path string) (map[string]interface{}, bool) {
+	var out map[string]interface{}
+	for k, v := range m {
+		fieldPath := strings.ToLower(k)
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+		newV, keep, changed := p.redactField(k, fieldPath, v)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(m))
+			for k2, v2 := range m {
+				out[k2] = v2
+			}
+		}
+		if keep {
+			out[k] = newV
+		} else {
+			delete(out, k)
+		}
+	}
+	if out == nil {
+		return m, false
+	}
+	return out, true
+}
+
+// redactField returns the redacted value of the field `key` at `path`, and whether the field
+// should be kept and whether it changed.
+func (p *Policy) redactField(key, path string, v interface{}) (interface{}, bool, bool) {
+	if rule, ok := p.paths[path]; ok {
+		return rule.redactWhole(v)
+	}
+	if rule, ok := p.keys[strings.ToLower(key)]; ok {
+		return rule.redactWhole(v)
+	}
+	return p.redactValue(v, path)
+}
+
+// redactValue applies key, path and value rules inside v.
+func (p *Policy) redactValue(v interface{}, path string) (interface{}, bool, bool) {
+	switch val := v.(type) {
+	case string:
+		return p.redactString(val)
+	case error, fmt.Stringer:
+		if len(p.values) == 0 {
+			return v, true, false
+		}
+		out, keep, changed := p.redactString(fmt.Sprint(val))
+		if !changed {
+			return v, true, false
+		}
+		return out, keep, true
+	case map[string]interface{}:
+		out, changed := p.redactMap(val, path)
+		return out, true, changed
+	case []interface{}:
+		return p.redactSlice(val, path)
+	case nil:
+		return v, true, false
+	}
+
+	// other maps with string keys, like logger.M, and slices are redacted as their generic
+	// equivalents
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v, true, false
+		}
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		out, changed := p.redactMap(m, path)
+		if !changed {
+			return v, true, false
+		}
+		return out, true, true
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v, true, false
+		}
+		s := make([]interface{}, rv.Len())
+		for i := range s {
+			s[i] = rv.Index(i).Interface()
+		}
+		out, keep, changed := p.redactSlice(s, path)
+		if !changed {
+			return v, true, false
+		}
+		return out, keep, true
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return v, true, false
+		}
+		out, keep, changed := p.redactValue(rv.Elem().Interface(), path)
+		if !changed {
+			return v, true, false
+		}
+		return out, keep, true
+	case reflect.Struct:
+		// structs are redacted as the JSON objects they are logged as
+		generic, ok := jsonValue(v)
+		if !ok {
+			return v, true, false
+		}
+		out, keep, changed := p.redactValue(generic, path)
+		if !changed {
+			return v, true, false
+		}
+		return out, keep, true
+	}
+	return v, true, false
+}
+
+// jsonValue returns v as it would be decoded from its JSON encoding, e.g. a struct becomes a
+// map[string]interface{}. Numbers are decoded as json.Number so they are written unchanged.
+func jsonValue(v interface{}) (interface{}, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var out interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func (p *Policy) redactSlice(s []interface{}, path string) (interface{}, bool, bool) {
+	var out []interface{}
+	for i, elem := range s {
+		newElem, keep, changed := p.redactValue(elem, path)
+		if changed && out == nil {
+			out = make([]interface{}, i, len(s))
+			copy(out, s[:i])
+		}
+		if out != nil && keep {
+			out = append(out, newElem)
+		}
+	}
+	if out == nil {
+		return s, true, false
+	}
+	return out, true, true
+}
+
+func (p *Policy) redactString(s string) (interface{}, bool, bool) {
+	out := s
+	for _, vr := range p.values {
+		if !vr.re.MatchString(out) {
+			continue
+		}
+		switch vr.rule.Action {
+		case Drop:
+			return nil, false, true
+		case Hash:
+			out = vr.re.ReplaceAllStringFunc(out, vr.rule.hash)
+		default:
+			out = vr.re.ReplaceAllLiteralString(out, vr.rule.Replacement)
+		}
+	}
+	return out, true, out != s
+}
+
+// redactWhole applies the rule's action to the whole value v.
+func (r *Rule) redactWhole(v interface{}) (interface{}, bool, bool) {
+	switch r.Action {
+	case Drop:
+		return nil, false, true
+	case Hash:
+		if s, ok := v.(string); ok && hashedValue.MatchString(s) {
+			return v, true, false
+		}
+		return r.hash(fmt.Sprintf("%v", v)), true, true
+	default:
+		return r.Replacement, true, v != r.Replacement
+	}
+}
+
+func (r *Rule) hash(s string) string {
+	var sum []byte
+	if r.HashKey != "" {
+		mac := hmac.New(sha256.New, []byte(r.HashKey))
+		mac.Write([]byte(s))
+		sum = mac.Sum(nil)
+	} else {
+		h := sha256.Sum256([]byte(s))
+		sum = h[:]
+	}
+	return hashPrefix + hex.EncodeToString(sum[:8])
+}
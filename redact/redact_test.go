@@ -0,0 +1,202 @@
+package redact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stringMap map[string]interface{}
+
+func TestRedactKeysAndPaths(t *testing.T) {
+	p, err := New([]Rule{
+		{Keys: []string{"Password", "authorization"}, Action: Drop},
+		{Keys: []string{"token"}},
+		{Paths: []string{"student.email"}, Action: Hash},
+		{Paths: []string{"teacher.name"}, Replacement: "***"},
+	})
+	require.NoError(t, err)
+
+	nested := map[string]interface{}{"email": "a@example.com", "id": 1}
+	data := map[string]interface{}{
+		"title":    "login",
+		"PASSWORD": "hunter2",
+		"headers":  map[string]interface{}{"Authorization": "Bearer abc", "Accept": "json"},
+		"token":    12345,
+		"student":  nested,
+		"teacher":  stringMap{"name": "Ms. Frizzle"},
+		"email":    "not-a-path-match",
+	}
+	out := p.Apply(data)
+
+	assert.Equal(t, map[string]interface{}{
+		"title":   "login",
+		"headers": map[string]interface{}{"Accept": "json"},
+		"token":   DefaultReplacement,
+		"student": map[string]interface{}{"email": out["student"].(map[string]interface{})["email"], "id": 1},
+		"teacher": map[string]interface{}{"name": "***"},
+		"email":   "not-a-path-match",
+	}, out)
+	assert.Regexp(t, `^sha256:[0-9a-f]{16}$`, out["student"].(map[string]interface{})["email"])
+
+	t.Log("the input and the maps nested in it are not modified")
+	assert.Equal(t, "hunter2", data["PASSWORD"])
+	assert.Equal(t, "a@example.com", nested["email"])
+	assert.Equal(t, "Bearer abc", data["headers"].(map[string]interface{})["Authorization"])
+
+	t.Log("applying the policy again changes nothing")
+	assert.Equal(t, out, p.Apply(out))
+}
+
+func TestRedactValues(t *testing.T) {
+	p, err := New([]Rule{
+		{Values: []string{"email"}, Action: Hash, HashKey: "secret"},
+		{Values: []string{"bearer_token", "ssn"}},
+		{Values: []string{`^drop-me`}, Action: Drop},
+	})
+	require.NoError(t, err)
+
+	data := map[string]interface{}{
+		"msg":    "sent to a@example.com and b@example.com",
+		"auth":   "bearer abc.def-123",
+		"ssns":   []interface{}{"123-45-6789", "drop-me please", 7},
+		"nested": map[string]interface{}{"note": "drop-me"},
+		"count":  3,
+	}
+	out := p.Apply(data)
+
+	assert.Regexp(t, `^sent to sha256:[0-9a-f]{16} and sha256:[0-9a-f]{16}$`, out["msg"])
+	assert.Equal(t, "[REDACTED]", out["auth"])
+	assert.Equal(t, []interface{}{"[REDACTED]", 7}, out["ssns"])
+	assert.Equal(t, map[string]interface{}{}, out["nested"])
+	assert.Equal(t, 3, out["count"])
+	assert.Equal(t, []interface{}{"123-45-6789", "drop-me please", 7}, data["ssns"])
+	assert.Equal(t, out, p.Apply(out))
+
+	unkeyed, err := New([]Rule{{Values: []string{"email"}, Action: Hash}})
+	require.NoError(t, err)
+	assert.NotEqual(t, out["msg"], unkeyed.Apply(data)["msg"], "HashKey should change the hash")
+}
+
+func TestRedactErrorsAndStringers(t *testing.T) {
+	p, err := New([]Rule{{Values: []string{"email"}}})
+	require.NoError(t, err)
+	unmatched := errors.New("not found")
+	data := map[string]interface{}{
+		"error":     fmt.Errorf("no user a@example.com"),
+		"unmatched": unmatched,
+		"url":       &url.URL{Scheme: "mailto", Opaque: "a@example.com"},
+	}
+	out := p.Apply(data)
+	assert.Equal(t, "no user [REDACTED]", out["error"])
+	assert.Equal(t, unmatched, out["unmatched"], "values that don't match keep their type")
+	assert.Equal(t, "mailto:[REDACTED]", out["url"])
+}
+
+func TestRedactStructsAndPointers(t *testing.T) {
+	type user struct {
+		ID       int               `json:"id"`
+		Email    string            `json:"email"`
+		Password string            `json:"password"`
+		Tags     map[string]string `json:"tags,omitempty"`
+	}
+	type noSecrets struct {
+		ID int `json:"id"`
+	}
+	p, err := New([]Rule{
+		{Keys: []string{"password"}, Action: Drop},
+		{Values: []string{"email"}},
+	})
+	require.NoError(t, err)
+	u := user{ID: 1, Email: "a@example.com", Password: "hunter2"}
+	m := map[string]interface{}{"password": "hunter2"}
+	unchanged := &noSecrets{ID: 2}
+	data := map[string]interface{}{
+		"user":      u,
+		"user_ptr":  &u,
+		"map_ptr":   &m,
+		"str_ptr":   &u.Email,
+		"unchanged": unchanged,
+		"nil_ptr":   (*user)(nil),
+	}
+
+	out := p.Apply(data)
+	redactedUser := map[string]interface{}{"id": json.Number("1"), "email": "[REDACTED]"}
+	assert.Equal(t, redactedUser, out["user"])
+	assert.Equal(t, redactedUser, out["user_ptr"])
+	assert.Equal(t, map[string]interface{}{}, out["map_ptr"])
+	assert.Equal(t, "[REDACTED]", out["str_ptr"])
+	assert.Same(t, unchanged, out["unchanged"], "values that don't change keep their type")
+	assert.Nil(t, out["nil_ptr"])
+	assert.Equal(t, "hunter2", u.Password, "input structs are not modified")
+	assert.Equal(t, "hunter2", m["password"], "input maps are not modified")
+	assert.Equal(t, out, p.Apply(out))
+}
+
+func TestRedactUnchangedReturnsInput(t *testing.T) {
+	p, err := New([]Rule{{Keys: []string{"password"}}})
+	require.NoError(t, err)
+	data := map[string]interface{}{"a": "b", "nested": map[string]interface{}{"c": "d"}}
+	out := p.Apply(data)
+	out["e"] = "f"
+	assert.Equal(t, "f", data["e"], "Apply should not copy lines it doesn't change")
+
+	var nilPolicy *Policy
+	assert.Equal(t, data, nilPolicy.Apply(data))
+}
+
+func TestNewInvalidRules(t *testing.T) {
+	for _, rules := range [][]Rule{
+		{{Keys: []string{"a"}, Action: "encrypt"}},
+		{{Values: []string{"("}}},
+		{{Action: Drop}},
+	} {
+		_, err := New(rules)
+		assert.Error(t, err)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kayvee-redact")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "kvconfig.yml")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(`
+routes: {}
+redaction:
+  - keys: [password]
+    action: drop
+  - paths: [student.email]
+    action: hash
+  - values: [ssn]
+    replacement: "<ssn>"
+`), 0644))
+
+	p, err := FromConfig(filename)
+	require.NoError(t, err)
+	out := p.Apply(map[string]interface{}{
+		"password": "hunter2",
+		"student":  map[string]interface{}{"email": "a@example.com"},
+		"note":     "ssn 123-45-6789",
+	})
+	assert.NotContains(t, out, "password")
+	assert.Regexp(t, `^sha256:`, out["student"].(map[string]interface{})["email"])
+	assert.Equal(t, "ssn <ssn>", out["note"])
+
+	p, err = FromConfigBytes([]byte("routes: {}\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+
+	_, err = FromConfigBytes([]byte("redaction:\n  - keys: [a]\n    action: encrypt\n"))
+	assert.Error(t, err)
+	_, err = FromConfig(filepath.Join(dir, "missing.yml"))
+	assert.Error(t, err)
+}
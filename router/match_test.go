@@ -5,6 +5,7 @@ import (
 	"sort"
 	"testing"
 
+	kv "github.com/Clever/kayvee-go/v7"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -100,6 +101,29 @@ func TestMatchesNested(t *testing.T) {
 	assert.False(t, r.Matches(msg4))
 }
 
+func TestMatchesLogfmt(t *testing.T) {
+	r := Rule{
+		Matchers: RuleMatchers{
+			"title":   []string{"greeting"},
+			"foo.bar": []string{"hello world"},
+			"ok":      []string{"true"},
+		},
+		Output: RuleOutput{},
+	}
+	line := kv.FormatLogfmt(map[string]interface{}{
+		"title": "greeting",
+		"foo":   map[string]interface{}{"bar": "hello world"},
+		"ok":    true,
+	})
+	msg, err := kv.ParseLogfmt(line)
+	assert.NoError(t, err)
+	assert.True(t, r.Matches(msg))
+
+	msg, err = kv.ParseLogfmt(`title=greeting foo.bar="hello there" ok=true`)
+	assert.NoError(t, err)
+	assert.False(t, r.Matches(msg))
+}
+
 func TestWildcardMatches(t *testing.T) {
 	assert := assert.New(t)
 	r := Rule{
@@ -9,14 +9,18 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-func parse(fileBytes []byte) (map[string]Rule, error) {
-	var config struct {
-		Routes map[string]Rule `json:"routes"`
-	}
+// kvConfig is a log-routing configuration file.
+type kvConfig struct {
+	Routes map[string]Rule `json:"routes"`
+	Levels *LevelOverrides `json:"levels,omitempty"`
+}
+
+func parse(fileBytes []byte) (kvConfig, error) {
+	var config kvConfig
 	// Unmarshaling also validates the config
 	err := yaml.Unmarshal(fileBytes, &config)
 	if err != nil {
-		return config.Routes, err
+		return config, err
 	}
 
 	schemaLoader := gojsonschema.NewStringLoader(routerSchema)
@@ -24,10 +28,10 @@ func parse(fileBytes []byte) (map[string]Rule, error) {
 
 	err = validate(schemaLoader, docLoader)
 	if err != nil {
-		return config.Routes, err
+		return config, err
 	}
 
-	return config.Routes, nil
+	return config, nil
 }
 
 func validate(schemaLoader, docLoader gojsonschema.JSONLoader) error {
@@ -373,3 +373,89 @@ routes:
 	_, err = NewFromConfigBytes(invalidConf)
 	assert.Error(t, err)
 }
+
+func TestHistogramStatType(t *testing.T) {
+	confTmpl := `
+routes:
+  latency:
+    matchers:
+      title: ["request-finished"]
+    output:
+      type: "%s"
+      series: "latency"
+      dimensions: ["op"]
+      value_field: "response-time-ms"%s
+`
+	for _, outputType := range []string{"metrics", "alerts"} {
+		validConf := []byte(fmt.Sprintf(confTmpl, outputType, `
+      stat_type: "histogram"
+      buckets: [5, 10, 50.5, 100]`))
+		router, err := NewFromConfigBytes(validConf)
+		assert.Nil(t, err)
+		r, ok := router.(*RuleRouter)
+		assert.True(t, ok)
+		assert.Equal(t, []interface{}{5, 10, 50.5, 100}, r.rules[0].Output["buckets"])
+
+		validConf = []byte(fmt.Sprintf(confTmpl, outputType, `
+      stat_type: "histogram"`))
+		_, err = NewFromConfigBytes(validConf)
+		assert.Nil(t, err)
+
+		invalidConf := []byte(fmt.Sprintf(confTmpl, outputType, `
+      stat_type: "gauge"
+      buckets: [5, 10]`))
+		_, err = NewFromConfigBytes(invalidConf)
+		assert.Error(t, err, "buckets require stat_type histogram")
+
+		invalidConf = []byte(fmt.Sprintf(confTmpl, outputType, `
+      stat_type: "histogram"
+      buckets: ["5"]`))
+		_, err = NewFromConfigBytes(invalidConf)
+		assert.Error(t, err, "buckets must be numbers")
+
+		invalidConf = []byte(fmt.Sprintf(confTmpl, outputType, `
+      stat_type: "summary"`))
+		_, err = NewFromConfigBytes(invalidConf)
+		assert.Error(t, err)
+	}
+}
+
+func TestLevelOverrides(t *testing.T) {
+	conf := []byte(`
+routes:
+  rule-one:
+    matchers:
+      title: ["authorize-app"]
+    output:
+      type: "analytics"
+      series: "series"
+levels:
+  titles:
+    db-*: warning
+    authorize-app: debug
+  sources:
+    noisy-worker: error
+`)
+	_, err := NewFromConfigBytes(conf)
+	assert.Nil(t, err)
+	levels, err := LevelOverridesFromConfigBytes(conf)
+	assert.Nil(t, err)
+	assert.Equal(t, LevelOverrides{
+		Titles:  map[string]string{"db-*": "warning", "authorize-app": "debug"},
+		Sources: map[string]string{"noisy-worker": "error"},
+	}, levels)
+
+	levels, err = LevelOverridesFromConfigBytes([]byte("routes: {}\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, LevelOverrides{}, levels)
+
+	for _, invalid := range []string{
+		"routes: {}\nlevels:\n  titles:\n    db-*: loud\n",
+		"routes: {}\nlevels:\n  sources:\n    worker: 3\n",
+	} {
+		_, err = LevelOverridesFromConfigBytes([]byte(invalid))
+		assert.Error(t, err, invalid)
+		_, err = NewFromConfigBytes([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
@@ -89,12 +89,48 @@ func NewFromConfig(filename string) (Router, error) {
 // the root-level map in the file. Validation is performed as described in
 // parse.go.
 func NewFromConfigBytes(fileBytes []byte) (Router, error) {
-	routes, err := parse(fileBytes)
+	config, err := parse(fileBytes)
 	if err != nil {
 		return &RuleRouter{}, err
 	}
 
-	return NewFromRoutes(routes)
+	return NewFromRoutes(config.Routes)
+}
+
+// LevelOverridesFromConfig returns the level overrides in the "levels" section of the
+// configuration specified as yaml in `filename`, e.g.
+//
+//	levels:
+//	  titles:
+//	    db-*: warning
+//	  sources:
+//	    noisy-worker: error
+//
+// The whole file is validated as described in parse.go.
+func LevelOverridesFromConfig(filename string) (LevelOverrides, error) {
+	fileBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return LevelOverrides{}, err
+	}
+
+	levels, err := LevelOverridesFromConfigBytes(fileBytes)
+	if err != nil {
+		return levels, fmt.Errorf(
+			"Error reading kayvee level overrides from file '%s':\n%s",
+			filename, err.Error(),
+		)
+	}
+	return levels, nil
+}
+
+// LevelOverridesFromConfigBytes is like LevelOverridesFromConfig, with the configuration
+// passed as bytes.
+func LevelOverridesFromConfigBytes(fileBytes []byte) (LevelOverrides, error) {
+	config, err := parse(fileBytes)
+	if err != nil || config.Levels == nil {
+		return LevelOverrides{}, err
+	}
+	return *config.Levels, nil
 }
 
 // NewFromRoutes constructs a RuleRouter using the provided map of route names
@@ -3,10 +3,11 @@
 package router
 
 var routerSchema = `{
-  "description": "Last modified: 11/05/2019",
+  "description": "Last modified: 10/16/2026",
   "required": ["routes"],
   "properties": {
-    "routes": { "$ref": "#/definitions/routes" }
+    "routes": { "$ref": "#/definitions/routes" },
+    "levels": { "$ref": "#/definitions/levels" }
   },
   "definitions": {
     "routes": {
@@ -16,6 +17,22 @@ var routerSchema = `{
         "^[a-zA-Z0-9-_]+$": { "$ref": "#/definitions/rule" }
       }
     },
+    "levels": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "titles": { "$ref": "#/definitions/levelMap" },
+        "sources": { "$ref": "#/definitions/levelMap" }
+      }
+    },
+    "levelMap": {
+      "type": "object",
+      "additionalProperties": { "$ref": "#/definitions/logLevel" }
+    },
+    "logLevel": {
+      "type": "string",
+      "enum": ["trace", "debug", "info", "warning", "error", "critical"]
+    },
     "rule": {
       "title": "Rule",
       "type": "object",
@@ -46,6 +63,7 @@ var routerSchema = `{
     "metricsOutput": {
       "title": "Metrics Output",
       "type": "object",
+      "dependencies": { "buckets": { "$ref": "#/definitions/histogramStatType" } },
       "oneOf": [
         {
           "additionalProperties": false,
@@ -53,7 +71,9 @@ var routerSchema = `{
           "properties": {
             "type": { "type": "string", "pattern": "^metrics$" },
             "series": { "$ref": "#/definitions/envVarSubstValue" },
-            "dimensions": { "$ref": "#/definitions/flatValueArr" }
+            "dimensions": { "$ref": "#/definitions/flatValueArr" },
+            "stat_type": { "$ref": "#/definitions/statType" },
+            "buckets": { "$ref": "#/definitions/buckets" }
           }
         },
         {
@@ -63,13 +83,16 @@ var routerSchema = `{
             "type": { "type": "string", "pattern": "^metrics$" },
             "series": { "$ref": "#/definitions/envVarSubstValue" },
             "dimensions": { "$ref": "#/definitions/flatValueArr" },
-            "value_field": { "$ref": "#/definitions/flatValue" }
+            "value_field": { "$ref": "#/definitions/flatValue" },
+            "stat_type": { "$ref": "#/definitions/statType" },
+            "buckets": { "$ref": "#/definitions/buckets" }
           }
         }
       ]
     },
     "alertsOutput": {
       "type": "object",
+      "dependencies": { "buckets": { "$ref": "#/definitions/histogramStatType" } },
       "oneOf": [
         {
           "additionalProperties": false,
@@ -82,7 +105,8 @@ var routerSchema = `{
               "uniqueItems": true,
               "items": { "$ref": "#/definitions/flatValue" }
             },
-            "stat_type": { "type": "string", "enum": ["counter", "gauge"] }
+            "stat_type": { "$ref": "#/definitions/statType" },
+            "buckets": { "$ref": "#/definitions/buckets" }
           }
         },
         {
@@ -96,8 +120,9 @@ var routerSchema = `{
               "uniqueItems": true,
               "items": { "$ref": "#/definitions/flatValue" }
             },
-            "stat_type": { "type": "string", "enum": ["counter", "gauge"] },
-            "value_field": { "$ref": "#/definitions/flatValue" }
+            "stat_type": { "$ref": "#/definitions/statType" },
+            "value_field": { "$ref": "#/definitions/flatValue" },
+            "buckets": { "$ref": "#/definitions/buckets" }
           }
         }
       ]
@@ -129,6 +154,22 @@ var routerSchema = `{
         "user": { "$ref": "#/definitions/envVarSubstValue" }
       }
     },
+    "statType": {
+      "type": "string",
+      "enum": ["counter", "gauge", "histogram"]
+    },
+    "buckets": {
+      "type": "array",
+      "minItems": 1,
+      "uniqueItems": true,
+      "items": { "type": "number" }
+    },
+    "histogramStatType": {
+      "required": ["stat_type"],
+      "properties": {
+        "stat_type": { "type": "string", "enum": ["histogram"] }
+      }
+    },
     "flatValue": {
       "type": "string",
       "pattern": "^[^%\\${}]+$"
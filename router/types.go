@@ -12,6 +12,13 @@ type RuleRouter struct {
 	rules []Rule
 }
 
+// LevelOverrides are the minimum log levels set in the `levels` section of a log-routing
+// configuration. Titles is keyed by title glob, e.g. "db-*", and Sources by source.
+type LevelOverrides struct {
+	Titles  map[string]string `json:"titles,omitempty"`
+	Sources map[string]string `json:"sources,omitempty"`
+}
+
 // RuleMatchers describes which log lines a router rule applies to.
 type RuleMatchers map[string][]string
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	kv "github.com/Clever/kayvee-go/v7"
 	"github.com/Clever/kayvee-go/v7/logger"
 )
 
@@ -51,6 +52,16 @@ func (e *InvalidJSONError) Error() string {
 	return fmt.Sprintf("Invalid JSON: %s", e.jsonError)
 }
 
+// InvalidLogfmtError is returned by `ValidateLogfmtFormat` for log lines that
+// aren't valid logfmt.
+type InvalidLogfmtError struct {
+	logfmtError error
+}
+
+func (e *InvalidLogfmtError) Error() string {
+	return fmt.Sprintf("Invalid logfmt: %s", e.logfmtError)
+}
+
 // MissingRequiredFieldError is returned for log lines that don't contain a
 // required field.
 // NOTE: Certain fields are only required for specific log types.
@@ -101,6 +112,17 @@ func ValidateJSONFormat(logLine string) error {
 	return validateKayveeData(kayveeData)
 }
 
+// ValidateLogfmtFormat returns a errors if the given string is not a valid
+// logfmt-formatted kayvee log line, like those written by `kv.FormatLogfmt`.
+func ValidateLogfmtFormat(logLine string) error {
+	kayveeData, err := kv.ParseLogfmt(strings.TrimSpace(logLine))
+	if err != nil {
+		return &InvalidLogfmtError{logfmtError: err}
+	}
+
+	return validateKayveeData(kayveeData)
+}
+
 func validateKayveeData(kayveeData map[string]interface{}) error {
 	for field, value := range kayveeData {
 		switch expectedValueTypes[field] {
@@ -121,7 +143,7 @@ func validateKayveeData(kayveeData map[string]interface{}) error {
 	if kayveeData["type"] != nil {
 		logType, _ := kayveeData["type"].(string)
 		switch logType {
-		case "gauge":
+		case "gauge", "histogram":
 			requiredFields = append(requiredFields, "value")
 		}
 	}
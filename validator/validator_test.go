@@ -35,6 +35,12 @@ func TestValidateJSONFormatFailsForMissingRequiredFields(t *testing.T) {
 		ValidateJSONFormat(`{"source":"an-app", "title":"an_event", "level":"error", "type":"gauge"}`),
 		&MissingRequiredFieldError{Field: "value"},
 	)
+
+	assert.Equal(
+		t,
+		ValidateJSONFormat(`{"source":"an-app", "title":"an_event", "level":"info", "type":"histogram"}`),
+		&MissingRequiredFieldError{Field: "value"},
+	)
 }
 
 func TestValidateJSONFormatFailsForInvalidLogType(t *testing.T) {
@@ -99,4 +105,35 @@ func TestValidateJSONFormatPassesForValidLogLines(t *testing.T) {
 		`,
 		),
 	)
+
+	assert.NoError(
+		t,
+		ValidateJSONFormat(`{
+			"source":"an-app",
+			"title":"an_event",
+			"level":"info",
+			"type":"histogram",
+			"value":0.25
+		}`),
+	)
+}
+
+func TestValidateLogfmtFormat(t *testing.T) {
+	assert.Nil(t, ValidateLogfmtFormat(`level=info source=an-app title=an_event type=gauge value=1`))
+	assert.IsType(t, &InvalidLogfmtError{}, ValidateLogfmtFormat(`level=info title="unterminated`))
+	assert.Equal(
+		t,
+		ValidateLogfmtFormat(`level=error title=an_event`),
+		&MissingRequiredFieldError{Field: "source"},
+	)
+	assert.Equal(
+		t,
+		ValidateLogfmtFormat(`level=info source=an-app title=an_event type=gauge value="1"`),
+		&InvalidValueTypeError{Field: "value", ExpectedType: NumberType},
+	)
+	assert.Equal(
+		t,
+		ValidateLogfmtFormat(`level=loud source=an-app title=an_event`),
+		&InvalidValueError{Field: "level", Value: "loud"},
+	)
 }